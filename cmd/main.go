@@ -17,12 +17,15 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"os"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/tigera/key-cert-provisioner/pkg/cfg"
 	"github.com/tigera/key-cert-provisioner/pkg/k8s"
+	"github.com/tigera/key-cert-provisioner/pkg/metrics"
 	"github.com/tigera/key-cert-provisioner/pkg/tls"
 )
 
@@ -32,27 +35,59 @@ func main() {
 	log.SetReportCaller(true)
 	// Initiate (and validate) env variables
 	config := cfg.GetConfigOrDie()
+
+	if config.MetricsAddr != "" {
+		go func() {
+			if err := metrics.Serve(context.Background(), config.MetricsAddr); err != nil {
+				log.WithError(err).Error("metrics server exited")
+			}
+		}()
+	}
+
+	restClient, err := k8s.NewRestClient()
+	if err != nil {
+		log.WithError(err).Fatalf("Unable to create a kubernetes rest restClient")
+	}
+
+	if config.Mode == cfg.ModeDaemon {
+		// In daemon mode this pod is expected to run (and renew its certificates) for its
+		// entire lifetime, so the crash-loop timeout below doesn't apply.
+		var wg sync.WaitGroup
+		errs := make([]error, len(config.CertificateRequests))
+		for i, req := range config.CertificateRequests {
+			wg.Add(1)
+			go func(i int, req cfg.CertificateRequest) {
+				defer wg.Done()
+				errs[i] = k8s.RunRenewalLoop(context.Background(), config.ForRequest(req), restClient)
+			}(i, req)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				log.WithError(err).Fatalf("Unable to run certificate renewal loop")
+			}
+		}
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.TODO(), config.TimeoutDuration)
 	defer cancel()
 	ch := make(chan int, 1)
 	go func() {
-		// Initiate REST restClient
-		restClient, err := k8s.NewRestClient()
-		if err != nil {
-			log.WithError(err).Fatalf("Unable to create a kubernetes rest restClient")
+		var wg sync.WaitGroup
+		errs := make([]error, len(config.CertificateRequests))
+		for i, req := range config.CertificateRequests {
+			wg.Add(1)
+			go func(i int, req cfg.CertificateRequest) {
+				defer wg.Done()
+				errs[i] = obtainCertificate(ctx, config.ForRequest(req), restClient)
+			}(i, req)
 		}
-
-		csr, err := tls.CreateX509CSR(config)
-		if err != nil {
-			log.WithError(err).Fatalf("Unable to create x509 certificate request")
-		}
-
-		if err := k8s.SubmitCSR(ctx, config, restClient, csr); err != nil {
-			log.WithError(err).Fatalf("Unable to submit a CSR")
-		}
-
-		if err := k8s.WatchCSR(ctx, restClient, config, csr); err != nil {
-			log.WithError(err).Fatalf("Unable to watch CSR")
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				log.WithError(err).Fatalf("Unable to obtain a certificate")
+			}
 		}
 		ch <- 0
 	}()
@@ -67,3 +102,13 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// obtainCertificate runs the one-shot issue-and-reload flow for a single certificate request,
+// via the issuer backend selected by config.IssuerBackend.
+func obtainCertificate(ctx context.Context, config *cfg.Config, restClient *k8s.RestClient) error {
+	csr, err := tls.CreateX509CSR(config)
+	if err != nil {
+		return fmt.Errorf("unable to create x509 certificate request: %w", err)
+	}
+	return k8s.IssueCertificate(ctx, config, restClient, csr)
+}
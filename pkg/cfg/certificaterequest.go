@@ -0,0 +1,63 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfg
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// CertificateRequest describes a single CSR this pod should obtain. Config.CertificateRequests
+// holds one entry per certificate; in the common single-certificate case it holds exactly one
+// entry built from the individual env vars rather than a CONFIG_FILE.
+type CertificateRequest struct {
+	Signer              string   `json:"signer"`
+	CommonName          string   `json:"commonName"`
+	EmailAddress        string   `json:"emailAddress,omitempty"`
+	DNSNames            []string `json:"dnsNames"`
+	PodIP               string   `json:"podIP,omitempty"`
+	KeyName             string   `json:"keyName"`
+	CertName            string   `json:"certName"`
+	CACertName          string   `json:"caCertName,omitempty"`
+	SignatureAlgorithm  string   `json:"signatureAlgorithm,omitempty"`
+	PrivateKeyAlgorithm string   `json:"keyAlgorithm,omitempty"`
+	// RenewBefore overrides Config.RenewBefore for this certificate when set, parsed with the
+	// same day/month/year suffixes as the RENEW_BEFORE env var (e.g. "720h", "30d", "6mo", "1y").
+	RenewBefore string `json:"renewBefore,omitempty"`
+	// SecretName overrides Config.SecretName for this certificate when SecretOutput is enabled.
+	// It is required when CONFIG_FILE defines more than one certificate request, since without it
+	// every request would write its Secret to the same name/namespace and race with the others.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// LoadCertificateRequestsFile reads a list of CertificateRequest entries from a YAML or JSON file
+// (sigs.k8s.io/yaml accepts both).
+func LoadCertificateRequestsFile(path string) ([]CertificateRequest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file %s: %w", path, err)
+	}
+
+	var requests []CertificateRequest
+	if err := yaml.Unmarshal(raw, &requests); err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %w", path, err)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("config file %s defines no certificate requests", path)
+	}
+	return requests, nil
+}
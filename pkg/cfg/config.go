@@ -17,11 +17,51 @@ package cfg
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 )
 
+// IssuerBackend selects which backend is used to turn a CSR into a signed certificate.
+type IssuerBackend string
+
+const (
+	// IssuerBackendKubeCSR drives the certificates.k8s.io CertificateSigningRequest API. This is the default.
+	IssuerBackendKubeCSR IssuerBackend = "kube-csr"
+	// IssuerBackendCertManager drives a cert-manager.io CertificateRequest.
+	IssuerBackendCertManager IssuerBackend = "cert-manager"
+	// IssuerBackendVault drives the Vault PKI secrets engine's sign endpoint directly.
+	IssuerBackendVault IssuerBackend = "vault"
+)
+
+// KeyProviderType selects where a certificate's private key is generated and who signs with it.
+type KeyProviderType string
+
+const (
+	// KeyProviderSoftware generates the private key in-process and writes it to EmptyDirLocation,
+	// same as always. This is the default.
+	KeyProviderSoftware KeyProviderType = "software"
+	// KeyProviderPKCS11 signs with a key held in a PKCS#11 token (an HSM or software token such
+	// as SoftHSM); the key never leaves the token and no key file is written.
+	KeyProviderPKCS11 KeyProviderType = "pkcs11"
+	// KeyProviderKMS signs with a key held in a cloud KMS (see Config.KMSProvider); the key
+	// never leaves the KMS and no key file is written.
+	KeyProviderKMS KeyProviderType = "kms"
+)
+
+// Mode selects whether the provisioner exits after writing a certificate once, or stays resident
+// and renews it.
+type Mode string
+
+const (
+	// ModeOneshot writes a single certificate and exits. This is the historical behavior.
+	ModeOneshot Mode = "oneshot"
+	// ModeDaemon stays resident and renews the certificate as it approaches expiry.
+	ModeDaemon Mode = "daemon"
+)
+
 // Config holds parameters that are used during runtime.
 type Config struct {
 	CSRName             string
@@ -37,6 +77,108 @@ type Config struct {
 	PrivateKeyAlgorithm string
 	RegisterApiserver   bool
 	AppName             string
+
+	// CACertPEM and CACertName hold a pre-configured CA bundle that is written alongside the
+	// certificate. An issuer backend that returns its own CA bundle takes precedence over this.
+	CACertPEM  []byte
+	CACertName string
+
+	// IssuerBackend selects which backend is used to sign the CSR created by pkg/tls.
+	IssuerBackend IssuerBackend
+
+	// IssuerRef, IssuerKind and IssuerGroup identify the cert-manager Issuer/ClusterIssuer to
+	// request from when IssuerBackend is IssuerBackendCertManager. IssuerGroup defaults to
+	// "cert-manager.io"; it only needs to be set when requesting from an external issuer
+	// (https://cert-manager.io/docs/configuration/external/).
+	IssuerRef       string
+	IssuerKind      string
+	IssuerGroup     string
+	IssuerNamespace string
+
+	// Duration requests a non-default validity period for the issued certificate when
+	// IssuerBackend is IssuerBackendCertManager. Zero leaves it to the Issuer's default.
+	Duration time.Duration
+
+	// VaultAddr, VaultPKIMount, VaultPKIRole and VaultTokenPath configure the Vault PKI issuer
+	// used when IssuerBackend is IssuerBackendVault. VaultTokenPath points at a projected
+	// service-account token file whose contents are sent as the X-Vault-Token header as they
+	// are; no Vault auth login is performed to exchange it for a Vault token first.
+	VaultAddr      string
+	VaultPKIMount  string
+	VaultPKIRole   string
+	VaultTokenPath string
+
+	// RenewBeforeRatio is the fraction of the certificate's validity period that must have
+	// elapsed before the renewal loop submits a fresh CSR. A value of 2.0/3.0 renews once
+	// two thirds of the lifetime has passed, leaving a third of the lifetime as headroom.
+	// Used only when RenewBefore is zero.
+	RenewBeforeRatio float64
+
+	// Mode selects oneshot (write once and exit, the historical behavior) or daemon (stay
+	// resident and renew) operation.
+	Mode Mode
+
+	// RenewBefore is a fixed amount of time before the certificate's expiry at which the
+	// renewal loop submits a fresh CSR, taking precedence over RenewBeforeRatio when set.
+	RenewBefore time.Duration
+
+	// RenewalCheckInterval caps how long the renewal loop ever sleeps in one go, so a pod
+	// whose clock jumps or that was suspended doesn't sleep through its renewal point.
+	RenewalCheckInterval time.Duration
+
+	// SecretOutput, when true, writes the issued certificate to a kubernetes.io/tls Secret in
+	// SecretNamespace/SecretName in addition to the emptyDir files, owned by OwnerPodName/
+	// OwnerPodUID so the Secret is garbage collected along with the pod.
+	SecretOutput    bool
+	SecretName      string
+	SecretNamespace string
+	OwnerPodName    string
+	OwnerPodUID     string
+
+	// CAInjectionTargets is a list of "<Kind>=<Name>" entries (e.g.
+	// "MutatingWebhookConfiguration=my-webhook") identifying additional objects that should
+	// have their caBundle kept in sync with the certificate this pod issues. See
+	// pkg/k8s.InjectCABundle for the supported kinds.
+	CAInjectionTargets []string
+
+	// ReloadCommand, if set, is exec'd after every successful certificate write, with the key
+	// path, cert path and common name passed through the environment. See pkg/reload.
+	ReloadCommand []string
+
+	// ReloadSignalPID and ReloadSignalPIDFile select a process to signal (with ReloadSignal,
+	// default SIGHUP) after every successful certificate write. ReloadSignalPIDFile is
+	// re-read on every write so it can be used with a sidecar whose PID isn't known up front.
+	ReloadSignalPID     int
+	ReloadSignalPIDFile string
+	ReloadSignal        string
+
+	// MetricsAddr, if set, is the bind address (e.g. ":9090") for the pkg/metrics HTTP server
+	// exposing /metrics, /healthz and /readyz. Left unset, no metrics server is started.
+	MetricsAddr string
+
+	// KeyProvider selects where the private key is generated and who signs the CSR with it.
+	// Defaults to KeyProviderSoftware.
+	KeyProvider KeyProviderType
+
+	// PKCS11Module, PKCS11Slot, PKCS11Pin and PKCS11Label configure the PKCS#11 token used when
+	// KeyProvider is KeyProviderPKCS11. PKCS11Label identifies the key object within the token,
+	// created there if it doesn't already exist.
+	PKCS11Module string
+	PKCS11Slot   uint
+	PKCS11Pin    string
+	PKCS11Label  string
+
+	// KMSProvider selects the cloud KMS used when KeyProvider is KeyProviderKMS ("gcp" or "aws").
+	// KMSKeyID is that KMS's resource identifier for the asymmetric signing key (a GCP
+	// CryptoKeyVersion name or an AWS KMS key ARN); KMSRegion is required for "aws".
+	KMSProvider string
+	KMSKeyID    string
+	KMSRegion   string
+
+	// CertificateRequests holds one entry per certificate this pod should obtain. When
+	// CONFIG_FILE is unset this always holds exactly one entry, built from the individual
+	// SIGNER/COMMON_NAME/DNS_NAMES/... env vars so callers can iterate it uniformly either way.
+	CertificateRequests []CertificateRequest
 }
 
 // GetEnvOrDie convenience method for initializing env.
@@ -49,23 +191,275 @@ func GetEnvOrDie(env string) string {
 }
 
 // GetConfigOrDie initializes the Config that this program relies on. It exists the program if expected variables are missing.
+//
+// By default it describes a single certificate, built from the SIGNER/COMMON_NAME/DNS_NAMES/...
+// env vars below. If CONFIG_FILE is set instead, those env vars are ignored in favor of the list
+// of certificate requests it contains, so a single pod can obtain more than one certificate (e.g.
+// a serving cert and a client cert from different signers) without running one sidecar per cert.
 func GetConfigOrDie() *Config {
-	dnsNames := strings.Split(os.Getenv("DNS_NAMES"), ",")
-	if len(dnsNames) == 0 {
-		log.Fatal("environment variable DNS_NAMES cannot be empty")
-	}
-	return &Config{
-		CSRName:             fmt.Sprintf("%s:%s", GetEnvOrDie("POD_NAMESPACE"), GetEnvOrDie("POD_NAME")),
-		SignatureAlgorithm:  os.Getenv("SIGNATURE_ALGORITHM"),
-		Signer:              GetEnvOrDie("SIGNER"),
-		CommonName:          GetEnvOrDie("COMMON_NAME"),
-		EmailAddress:        os.Getenv("EMAIL_ADDRESS"),
-		EmptyDirLocation:    GetEnvOrDie("CERTIFICATE_PATH"),
-		KeyName:             GetEnvOrDie("KEY_NAME"),
-		CertName:            GetEnvOrDie("CERT_NAME"),
-		PodIP:               GetEnvOrDie("POD_IP"),
-		AppName:             GetEnvOrDie("APP_NAME"),
-		PrivateKeyAlgorithm: os.Getenv("KEY_ALGORITHM"),
-		DNSNames:            dnsNames,
+	// POD_IP is pod-wide rather than per-signer, so it's read unconditionally and used as the
+	// fallback in ForRequest for any CertificateRequest (CONFIG_FILE entry or otherwise) that
+	// doesn't set its own.
+	podIP := GetEnvOrDie("POD_IP")
+
+	var certificateRequests []CertificateRequest
+	var singleRequest CertificateRequest
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile != "" {
+		requests, err := LoadCertificateRequestsFile(configFile)
+		if err != nil {
+			log.Fatalf("unable to load CONFIG_FILE: %v", err)
+		}
+		certificateRequests = requests
+	} else {
+		dnsNames := strings.Split(os.Getenv("DNS_NAMES"), ",")
+		if len(dnsNames) == 0 {
+			log.Fatal("environment variable DNS_NAMES cannot be empty")
+		}
+		singleRequest = CertificateRequest{
+			Signer:              GetEnvOrDie("SIGNER"),
+			CommonName:          GetEnvOrDie("COMMON_NAME"),
+			EmailAddress:        os.Getenv("EMAIL_ADDRESS"),
+			DNSNames:            dnsNames,
+			PodIP:               podIP,
+			KeyName:             GetEnvOrDie("KEY_NAME"),
+			CertName:            GetEnvOrDie("CERT_NAME"),
+			CACertName:          os.Getenv("CA_CERT_NAME"),
+			SignatureAlgorithm:  os.Getenv("SIGNATURE_ALGORITHM"),
+			PrivateKeyAlgorithm: os.Getenv("KEY_ALGORITHM"),
+		}
+		certificateRequests = []CertificateRequest{singleRequest}
+	}
+
+	issuerBackend := IssuerBackend(os.Getenv("ISSUER_BACKEND"))
+	if issuerBackend == "" {
+		issuerBackend = IssuerBackendKubeCSR
+	}
+
+	secretOutput := false
+	if val := os.Getenv("SECRET_OUTPUT"); val != "" {
+		parsed, err := strconv.ParseBool(val)
+		if err != nil {
+			log.Fatalf("environment variable SECRET_OUTPUT must be a bool: %v", err)
+		}
+		secretOutput = parsed
+	}
+
+	// SecretName/SecretNamespace are single, pod-wide fields: with more than one certificate
+	// request and SecretOutput enabled, every request would otherwise write to the same Secret
+	// and race with the others writing theirs. Require each request to carry its own SecretName
+	// in that case.
+	if secretOutput && len(certificateRequests) > 1 {
+		for _, req := range certificateRequests {
+			if req.SecretName == "" {
+				log.Fatalf("CONFIG_FILE defines more than one certificate request and SECRET_OUTPUT is enabled: certificate request %q must set its own secretName", req.CommonName)
+			}
+		}
+	}
+
+	renewBeforeRatio := 2.0 / 3.0
+	if val := os.Getenv("RENEW_BEFORE_RATIO"); val != "" {
+		parsed, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			log.Fatalf("environment variable RENEW_BEFORE_RATIO must be a float: %v", err)
+		}
+		renewBeforeRatio = parsed
+	}
+
+	var renewBefore time.Duration
+	if val := os.Getenv("RENEW_BEFORE"); val != "" {
+		parsed, err := ParseDuration(val)
+		if err != nil {
+			log.Fatalf("environment variable RENEW_BEFORE is invalid: %v", err)
+		}
+		renewBefore = parsed
+	}
+
+	var duration time.Duration
+	if val := os.Getenv("DURATION"); val != "" {
+		parsed, err := ParseDuration(val)
+		if err != nil {
+			log.Fatalf("environment variable DURATION is invalid: %v", err)
+		}
+		duration = parsed
+	}
+
+	renewalCheckInterval := time.Hour
+	if val := os.Getenv("RENEWAL_CHECK_INTERVAL"); val != "" {
+		parsed, err := ParseDuration(val)
+		if err != nil {
+			log.Fatalf("environment variable RENEWAL_CHECK_INTERVAL is invalid: %v", err)
+		}
+		renewalCheckInterval = parsed
+	}
+
+	mode := Mode(os.Getenv("MODE"))
+	if mode == "" {
+		mode = ModeOneshot
+	}
+
+	keyProvider := KeyProviderType(os.Getenv("KEY_PROVIDER"))
+	if keyProvider == "" {
+		keyProvider = KeyProviderSoftware
+	}
+
+	var pkcs11Slot uint
+	if val := os.Getenv("PKCS11_SLOT"); val != "" {
+		parsed, err := strconv.ParseUint(val, 10, 32)
+		if err != nil {
+			log.Fatalf("environment variable PKCS11_SLOT must be an unsigned int: %v", err)
+		}
+		pkcs11Slot = uint(parsed)
+	}
+
+	var reloadSignalPID int
+	if val := os.Getenv("RELOAD_SIGNAL_PID"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil {
+			log.Fatalf("environment variable RELOAD_SIGNAL_PID must be an int: %v", err)
+		}
+		reloadSignalPID = parsed
+	}
+
+	config := &Config{
+		CSRName:              fmt.Sprintf("%s:%s", GetEnvOrDie("POD_NAMESPACE"), GetEnvOrDie("POD_NAME")),
+		SignatureAlgorithm:   singleRequest.SignatureAlgorithm,
+		Signer:               singleRequest.Signer,
+		CommonName:           singleRequest.CommonName,
+		EmailAddress:         singleRequest.EmailAddress,
+		EmptyDirLocation:     GetEnvOrDie("CERTIFICATE_PATH"),
+		KeyName:              singleRequest.KeyName,
+		CertName:             singleRequest.CertName,
+		PodIP:                podIP,
+		AppName:              GetEnvOrDie("APP_NAME"),
+		PrivateKeyAlgorithm:  singleRequest.PrivateKeyAlgorithm,
+		DNSNames:             singleRequest.DNSNames,
+		CACertName:           singleRequest.CACertName,
+		IssuerBackend:        issuerBackend,
+		IssuerRef:            os.Getenv("ISSUER_REF"),
+		IssuerKind:           os.Getenv("ISSUER_KIND"),
+		IssuerGroup:          os.Getenv("ISSUER_GROUP"),
+		IssuerNamespace:      os.Getenv("ISSUER_NAMESPACE"),
+		Duration:             duration,
+		VaultAddr:            os.Getenv("VAULT_ADDR"),
+		VaultPKIMount:        os.Getenv("VAULT_PKI_MOUNT"),
+		VaultPKIRole:         os.Getenv("VAULT_PKI_ROLE"),
+		VaultTokenPath:       os.Getenv("VAULT_TOKEN_PATH"),
+		RenewBeforeRatio:     renewBeforeRatio,
+		SecretOutput:         secretOutput,
+		SecretName:           os.Getenv("SECRET_NAME"),
+		SecretNamespace:      os.Getenv("POD_NAMESPACE"),
+		OwnerPodName:         os.Getenv("POD_NAME"),
+		OwnerPodUID:          os.Getenv("POD_UID"),
+		CAInjectionTargets:   splitNonEmpty(os.Getenv("CA_INJECTION_TARGETS"), ","),
+		Mode:                 mode,
+		RenewBefore:          renewBefore,
+		RenewalCheckInterval: renewalCheckInterval,
+		ReloadCommand:        splitNonEmpty(os.Getenv("RELOAD_COMMAND"), " "),
+		ReloadSignalPID:      reloadSignalPID,
+		ReloadSignalPIDFile:  os.Getenv("RELOAD_SIGNAL_PID_FILE"),
+		ReloadSignal:         os.Getenv("RELOAD_SIGNAL"),
+		MetricsAddr:          os.Getenv("METRICS_ADDR"),
+		KeyProvider:          keyProvider,
+		PKCS11Module:         os.Getenv("PKCS11_MODULE"),
+		PKCS11Slot:           pkcs11Slot,
+		PKCS11Pin:            os.Getenv("PKCS11_PIN"),
+		PKCS11Label:          os.Getenv("PKCS11_LABEL"),
+		KMSProvider:          os.Getenv("KMS_PROVIDER"),
+		KMSKeyID:             os.Getenv("KMS_KEY_ID"),
+		KMSRegion:            os.Getenv("KMS_REGION"),
+		CertificateRequests:  certificateRequests,
+	}
+
+	if configFile != "" {
+		// In CONFIG_FILE mode the single-certificate fields above were never populated (there's
+		// no single SIGNER/COMMON_NAME/... to read), so seed them from the first request. This
+		// keeps anything that still reads them directly, such as logging, meaningful rather than
+		// empty; the fan-out in cmd/main.go uses ForRequest for every entry, including the first.
+		config = config.ForRequest(certificateRequests[0])
+	}
+
+	return config
+}
+
+// ForRequest returns a copy of c with the per-certificate fields (Signer, CommonName, DNSNames,
+// ...) overridden from req. When c holds more than one certificate request, CSRName is also
+// suffixed with req.CommonName so that concurrently requesting multiple certificates from a
+// single pod doesn't collide on CSR names; with a single request CSRName is left unchanged.
+func (c *Config) ForRequest(req CertificateRequest) *Config {
+	clone := *c
+	clone.Signer = req.Signer
+	clone.CommonName = req.CommonName
+	clone.EmailAddress = req.EmailAddress
+	clone.DNSNames = req.DNSNames
+	if req.PodIP != "" {
+		// PodIP is pod-wide rather than per-signer: a request that doesn't set its own keeps
+		// inheriting the pod's real IP instead of losing it.
+		clone.PodIP = req.PodIP
+	}
+	clone.KeyName = req.KeyName
+	clone.CertName = req.CertName
+	clone.CACertName = req.CACertName
+	if req.SecretName != "" {
+		clone.SecretName = req.SecretName
+	}
+	if req.SignatureAlgorithm != "" {
+		clone.SignatureAlgorithm = req.SignatureAlgorithm
+	}
+	if req.PrivateKeyAlgorithm != "" {
+		clone.PrivateKeyAlgorithm = req.PrivateKeyAlgorithm
+	}
+	if req.RenewBefore != "" {
+		renewBefore, err := ParseDuration(req.RenewBefore)
+		if err != nil {
+			log.Fatalf("certificate request %s has an invalid renewBefore: %v", req.CommonName, err)
+		}
+		clone.RenewBefore = renewBefore
+	}
+	if len(c.CertificateRequests) > 1 {
+		clone.CSRName = fmt.Sprintf("%s:%s", c.CSRName, req.CommonName)
+	}
+	return &clone
+}
+
+// ParseDuration parses a duration string accepted by time.ParseDuration, plus the single-unit
+// "d" (day, 24 hours), "mo" (month, 30 days) and "y" (year, 365 days) suffixes Kruise-style
+// configs use, e.g. "720h", "30d", "6mo", "1y".
+//
+// The month suffix is "mo", not the bare "m" an earlier version of this function used: every
+// digit-then-"m" string (e.g. "6m") is also a valid native Go duration meaning minutes, so a
+// bare "m" can never be unambiguously read as months. "mo" isn't a Go duration unit, so it has
+// no such conflict; plain "90m" still means 90 minutes via the time.ParseDuration fallback below.
+func ParseDuration(s string) (time.Duration, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.ParseFloat(strings.TrimSuffix(s, "d"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid day duration %q: %w", s, err)
+		}
+		return time.Duration(days * 24 * float64(time.Hour)), nil
+	}
+	if strings.HasSuffix(s, "y") {
+		years, err := strconv.ParseFloat(strings.TrimSuffix(s, "y"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid year duration %q: %w", s, err)
+		}
+		return time.Duration(years * 365 * 24 * float64(time.Hour)), nil
+	}
+	if strings.HasSuffix(s, "mo") {
+		months, err := strconv.ParseFloat(strings.TrimSuffix(s, "mo"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid month duration %q: %w", s, err)
+		}
+		return time.Duration(months * 30 * 24 * float64(time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// splitNonEmpty splits s on sep, dropping the result entirely when s is empty so callers don't
+// have to special-case a single empty string the way strings.Split would produce.
+func splitNonEmpty(s, sep string) []string {
+	if s == "" {
+		return nil
 	}
+	return strings.Split(s, sep)
 }
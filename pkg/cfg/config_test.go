@@ -0,0 +1,78 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cfg_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+var _ = Describe("Test Config.ForRequest", func() {
+	var base *cfg.Config
+
+	BeforeEach(func() {
+		base = &cfg.Config{
+			PodIP:               "10.0.0.1",
+			SecretName:          "default-secret",
+			CertificateRequests: []cfg.CertificateRequest{{}, {}},
+		}
+	})
+
+	It("falls back to the pod's own PodIP when the request doesn't set one", func() {
+		clone := base.ForRequest(cfg.CertificateRequest{CommonName: "no-pod-ip"})
+		Expect(clone.PodIP).To(Equal("10.0.0.1"))
+	})
+
+	It("overrides PodIP when the request sets its own", func() {
+		clone := base.ForRequest(cfg.CertificateRequest{CommonName: "own-pod-ip", PodIP: "10.0.0.2"})
+		Expect(clone.PodIP).To(Equal("10.0.0.2"))
+	})
+
+	It("falls back to the pod-wide SecretName when the request doesn't set one", func() {
+		clone := base.ForRequest(cfg.CertificateRequest{CommonName: "no-secret-name"})
+		Expect(clone.SecretName).To(Equal("default-secret"))
+	})
+
+	It("overrides SecretName when the request sets its own", func() {
+		clone := base.ForRequest(cfg.CertificateRequest{CommonName: "own-secret-name", SecretName: "per-request-secret"})
+		Expect(clone.SecretName).To(Equal("per-request-secret"))
+	})
+})
+
+var _ = DescribeTable("Test ParseDuration",
+	func(s string, expected time.Duration) {
+		got, err := cfg.ParseDuration(s)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(got).To(Equal(expected))
+	},
+	Entry("hours", "720h", 720*time.Hour),
+	Entry("days", "30d", 30*24*time.Hour),
+	// "mo" (not the bare "m" time.ParseDuration already claims for minutes) is this package's
+	// month suffix; "90m" below proves that native minutes still take the time.ParseDuration path.
+	Entry("months", "6mo", 6*30*24*time.Hour),
+	Entry("native minutes", "90m", 90*time.Minute),
+	Entry("years", "1y", 365*24*time.Hour),
+)
+
+var _ = Describe("Test ParseDuration errors", func() {
+	It("rejects a string that is neither a native duration nor a d/mo/y suffix", func() {
+		_, err := cfg.ParseDuration("not-a-duration")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,126 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CAInjectionTargetKind identifies the kind of resource a CAInjectionTarget refers to.
+type CAInjectionTargetKind string
+
+const (
+	// CAInjectionTargetMutatingWebhook injects caBundle into every webhook entry of a
+	// MutatingWebhookConfiguration.
+	CAInjectionTargetMutatingWebhook CAInjectionTargetKind = "MutatingWebhookConfiguration"
+	// CAInjectionTargetValidatingWebhook injects caBundle into every webhook entry of a
+	// ValidatingWebhookConfiguration.
+	CAInjectionTargetValidatingWebhook CAInjectionTargetKind = "ValidatingWebhookConfiguration"
+	// CAInjectionTargetCRDConversion injects caBundle into a CustomResourceDefinition's
+	// spec.conversion.webhook.clientConfig.
+	CAInjectionTargetCRDConversion CAInjectionTargetKind = "CustomResourceDefinition"
+)
+
+// CAInjectionTarget identifies a single resource that should receive the caBundle.
+type CAInjectionTarget struct {
+	Kind CAInjectionTargetKind
+	Name string
+}
+
+// ParseCAInjectionTargets parses the "<Kind>=<Name>" entries in cfg.Config.CAInjectionTargets
+// into the CAInjectionTarget values InjectCABundle expects.
+func ParseCAInjectionTargets(raw []string) ([]CAInjectionTarget, error) {
+	targets := make([]CAInjectionTarget, 0, len(raw))
+	for _, entry := range raw {
+		kind, name, found := strings.Cut(entry, "=")
+		if !found || kind == "" || name == "" {
+			return nil, fmt.Errorf("invalid CA injection target %q, expected <Kind>=<Name>", entry)
+		}
+		targets = append(targets, CAInjectionTarget{Kind: CAInjectionTargetKind(kind), Name: name})
+	}
+	return targets, nil
+}
+
+// InjectCABundle patches caBundle into every target in targets, the same CA-injection job that
+// cert-manager's cainjector performs for certificates it manages. RegisterAPIService covers the
+// APIService case; this covers the admission and CRD conversion webhook cases, which are needed
+// whenever this provisioner issues the serving certificate for an admission or conversion
+// webhook rather than for an aggregated apiserver.
+func InjectCABundle(ctx context.Context, restClient *RestClient, targets []CAInjectionTarget, caBundle []byte) error {
+	for _, target := range targets {
+		var err error
+		switch target.Kind {
+		case CAInjectionTargetMutatingWebhook:
+			err = injectMutatingWebhookCABundle(ctx, restClient, target.Name, caBundle)
+		case CAInjectionTargetValidatingWebhook:
+			err = injectValidatingWebhookCABundle(ctx, restClient, target.Name, caBundle)
+		case CAInjectionTargetCRDConversion:
+			err = injectCRDConversionCABundle(ctx, restClient, target.Name, caBundle)
+		default:
+			err = fmt.Errorf("unknown CA injection target kind: %q", target.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to inject CA bundle into %s/%s: %w", target.Kind, target.Name, err)
+		}
+		log.Infof("injected CA bundle into %s/%s", target.Kind, target.Name)
+	}
+	return nil
+}
+
+func injectMutatingWebhookCABundle(ctx context.Context, restClient *RestClient, name string, caBundle []byte) error {
+	cli := restClient.Clientset.AdmissionregistrationV1().MutatingWebhookConfigurations()
+	existing, err := cli.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range existing.Webhooks {
+		existing.Webhooks[i].ClientConfig.CABundle = caBundle
+	}
+	_, err = cli.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func injectValidatingWebhookCABundle(ctx context.Context, restClient *RestClient, name string, caBundle []byte) error {
+	cli := restClient.Clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations()
+	existing, err := cli.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range existing.Webhooks {
+		existing.Webhooks[i].ClientConfig.CABundle = caBundle
+	}
+	_, err = cli.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func injectCRDConversionCABundle(ctx context.Context, restClient *RestClient, name string, caBundle []byte) error {
+	cli := restClient.ApiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions()
+	existing, err := cli.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if existing.Spec.Conversion == nil || existing.Spec.Conversion.Webhook == nil || existing.Spec.Conversion.Webhook.ClientConfig == nil {
+		return fmt.Errorf("CRD %s has no conversion webhook client config to inject a CA bundle into", name)
+	}
+	existing.Spec.Conversion.Webhook.ClientConfig.CABundle = caBundle
+	_, err = cli.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
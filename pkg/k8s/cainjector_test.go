@@ -0,0 +1,128 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/k8s"
+
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apiextensionsfake "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Test ParseCAInjectionTargets", func() {
+	It("parses <Kind>=<Name> entries", func() {
+		targets, err := k8s.ParseCAInjectionTargets([]string{"MutatingWebhookConfiguration=my-webhook"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(targets).To(Equal([]k8s.CAInjectionTarget{
+			{Kind: k8s.CAInjectionTargetMutatingWebhook, Name: "my-webhook"},
+		}))
+	})
+
+	It("errors on an entry with no '='", func() {
+		_, err := k8s.ParseCAInjectionTargets([]string{"my-webhook"})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Test InjectCABundle", func() {
+	ctx := context.Background()
+	caBundle := []byte("<ca bundle>")
+
+	It("patches caBundle into every webhook entry of a MutatingWebhookConfiguration", func() {
+		clientset := fake.NewSimpleClientset(&admissionv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-webhook"},
+			Webhooks: []admissionv1.MutatingWebhook{
+				{Name: "a.example.com"},
+				{Name: "b.example.com"},
+			},
+		})
+		restClient := &k8s.RestClient{Clientset: clientset}
+
+		targets := []k8s.CAInjectionTarget{{Kind: k8s.CAInjectionTargetMutatingWebhook, Name: "my-webhook"}}
+		Expect(k8s.InjectCABundle(ctx, restClient, targets, caBundle)).NotTo(HaveOccurred())
+
+		updated, err := clientset.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "my-webhook", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		for _, wh := range updated.Webhooks {
+			Expect(wh.ClientConfig.CABundle).To(Equal(caBundle))
+		}
+	})
+
+	It("patches caBundle into every webhook entry of a ValidatingWebhookConfiguration", func() {
+		clientset := fake.NewSimpleClientset(&admissionv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-webhook"},
+			Webhooks: []admissionv1.ValidatingWebhook{
+				{Name: "a.example.com"},
+				{Name: "b.example.com"},
+			},
+		})
+		restClient := &k8s.RestClient{Clientset: clientset}
+
+		targets := []k8s.CAInjectionTarget{{Kind: k8s.CAInjectionTargetValidatingWebhook, Name: "my-webhook"}}
+		Expect(k8s.InjectCABundle(ctx, restClient, targets, caBundle)).NotTo(HaveOccurred())
+
+		updated, err := clientset.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "my-webhook", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		for _, wh := range updated.Webhooks {
+			Expect(wh.ClientConfig.CABundle).To(Equal(caBundle))
+		}
+	})
+
+	It("patches caBundle into a CustomResourceDefinition's conversion webhook client config", func() {
+		apiExtensionsClient := apiextensionsfake.NewSimpleClientset(&apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Conversion: &apiextensionsv1.CustomResourceConversion{
+					Strategy: apiextensionsv1.WebhookConverter,
+					Webhook: &apiextensionsv1.WebhookConversion{
+						ClientConfig: &apiextensionsv1.WebhookClientConfig{},
+					},
+				},
+			},
+		})
+		restClient := &k8s.RestClient{ApiExtensionsClient: apiExtensionsClient}
+
+		targets := []k8s.CAInjectionTarget{{Kind: k8s.CAInjectionTargetCRDConversion, Name: "widgets.example.com"}}
+		Expect(k8s.InjectCABundle(ctx, restClient, targets, caBundle)).NotTo(HaveOccurred())
+
+		updated, err := apiExtensionsClient.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, "widgets.example.com", metav1.GetOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.Spec.Conversion.Webhook.ClientConfig.CABundle).To(Equal(caBundle))
+	})
+
+	It("errors for a CustomResourceDefinition with no conversion webhook client config", func() {
+		apiExtensionsClient := apiextensionsfake.NewSimpleClientset(&apiextensionsv1.CustomResourceDefinition{
+			ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.com"},
+		})
+		restClient := &k8s.RestClient{ApiExtensionsClient: apiExtensionsClient}
+
+		targets := []k8s.CAInjectionTarget{{Kind: k8s.CAInjectionTargetCRDConversion, Name: "widgets.example.com"}}
+		Expect(k8s.InjectCABundle(ctx, restClient, targets, caBundle)).To(HaveOccurred())
+	})
+
+	It("errors for an unknown target kind", func() {
+		restClient := &k8s.RestClient{Clientset: fake.NewSimpleClientset()}
+		targets := []k8s.CAInjectionTarget{{Kind: "Bogus", Name: "whatever"}}
+		Expect(k8s.InjectCABundle(ctx, restClient, targets, caBundle)).To(HaveOccurred())
+	})
+})
@@ -16,14 +16,20 @@ package k8s
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	stderrors "errors"
 	"fmt"
 	"os"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/metrics"
 	"github.com/tigera/key-cert-provisioner/pkg/tls"
 
 	certV1 "k8s.io/api/certificates/v1"
@@ -40,20 +46,12 @@ type versionInfo struct {
 	Minor int
 }
 
-// WatchCSR Watches the CSR resource for updates and writes results to the certificate location (which should be mounted as an emptyDir)
-func WatchCSR(ctx context.Context, restClient *RestClient, cfg *cfg.Config, x509CSR *tls.X509CSR) error {
-	version, err := GetKubernetesVersion(restClient.Clientset)
-	if err != nil {
-		return err
-	}
-
-	watcher, err := createCSRWatcher(ctx, restClient, version)
-	if err != nil {
-		return fmt.Errorf("unable to watch certificate requests: %w", err)
-	}
-	log.Infof("watching CSR until it has been signed and approved: %v", cfg.CSRName)
-	return watchCSRBasedOnKubernetesVersion(watcher, cfg, x509CSR, version)
-}
+// ErrCSRDenied and ErrCSRFailed let RecordCSRFailure distinguish, via errors.Is, why a CSR never
+// produced a certificate without parsing the wrapping error message.
+var (
+	ErrCSRDenied = stderrors.New("CSR was denied")
+	ErrCSRFailed = stderrors.New("CSR failed")
+)
 
 func createCSRWatcher(ctx context.Context, restClient *RestClient, version *versionInfo) (*watch.Interface, error) {
 	var watcher watch.Interface
@@ -66,18 +64,18 @@ func createCSRWatcher(ctx context.Context, restClient *RestClient, version *vers
 	return &watcher, err
 }
 
-func watchCSRBasedOnKubernetesVersion(watcher *watch.Interface, cfg *cfg.Config, x509CSR *tls.X509CSR, version *versionInfo) error {
+func watchCSRBasedOnKubernetesVersion(watcher *watch.Interface, cfg *cfg.Config, version *versionInfo) ([]byte, error) {
 	if version.Major > 1 || version.Minor >= 19 {
-		return watchCSRUsingCertV1(watcher, cfg, x509CSR)
+		return watchCSRUsingCertV1(watcher, cfg)
 	}
-	return watchCSRUsingCertV1beta1(watcher, cfg, x509CSR)
+	return watchCSRUsingCertV1beta1(watcher, cfg)
 }
 
-func watchCSRUsingCertV1(watcher *watch.Interface, cfg *cfg.Config, x509CSR *tls.X509CSR) error {
+func watchCSRUsingCertV1(watcher *watch.Interface, cfg *cfg.Config) ([]byte, error) {
 	for event := range (*watcher).ResultChan() {
 		chcsr, ok := event.Object.(*certV1.CertificateSigningRequest)
 		if !ok {
-			return fmt.Errorf("unexpected type in CertificateSigningRequest channel: %o", event.Object)
+			return nil, fmt.Errorf("unexpected type in CertificateSigningRequest channel: %o", event.Object)
 		}
 		if chcsr.Name == cfg.CSRName && chcsr.Status.Conditions != nil && len(chcsr.Status.Certificate) > 0 {
 			approved := false
@@ -87,25 +85,25 @@ func watchCSRUsingCertV1(watcher *watch.Interface, cfg *cfg.Config, x509CSR *tls
 					break
 				}
 				if c.Type == certV1.CertificateDenied && c.Status == v1.ConditionTrue {
-					return fmt.Errorf("CSR was denied for this pod. CSR name: %s", cfg.CSRName)
+					return nil, fmt.Errorf("%w for this pod. CSR name: %s", ErrCSRDenied, cfg.CSRName)
 				}
 				if c.Type == certV1.CertificateFailed && c.Status == v1.ConditionTrue {
-					return fmt.Errorf("CSR failed for this pod. CSR name: %s", cfg.CSRName)
+					return nil, fmt.Errorf("%w for this pod. CSR name: %s", ErrCSRFailed, cfg.CSRName)
 				}
 			}
 			if approved {
-				return WriteCertificateToFile(cfg, chcsr.Status.Certificate, x509CSR)
+				return chcsr.Status.Certificate, nil
 			}
 		}
 	}
-	return nil
+	return nil, nil
 }
 
-func watchCSRUsingCertV1beta1(watcher *watch.Interface, cfg *cfg.Config, x509CSR *tls.X509CSR) error {
+func watchCSRUsingCertV1beta1(watcher *watch.Interface, cfg *cfg.Config) ([]byte, error) {
 	for event := range (*watcher).ResultChan() {
 		chcsr, ok := event.Object.(*certV1beta1.CertificateSigningRequest)
 		if !ok {
-			return fmt.Errorf("unexpected type in CertificateSigningRequest channel: %o", event.Object)
+			return nil, fmt.Errorf("unexpected type in CertificateSigningRequest channel: %o", event.Object)
 		}
 		if chcsr.Name == cfg.CSRName && chcsr.Status.Conditions != nil && len(chcsr.Status.Certificate) > 0 {
 			approved := false
@@ -116,39 +114,46 @@ func watchCSRUsingCertV1beta1(watcher *watch.Interface, cfg *cfg.Config, x509CSR
 					break
 				}
 				if c.Type == certV1beta1.CertificateDenied && c.Status == v1.ConditionTrue {
-					return fmt.Errorf("CSR was denied for this pod. CSR name: %s", cfg.CSRName)
+					return nil, fmt.Errorf("%w for this pod. CSR name: %s", ErrCSRDenied, cfg.CSRName)
 				}
 				if c.Type == certV1beta1.CertificateFailed && c.Status == v1.ConditionTrue {
-					return fmt.Errorf("CSR failed for this pod. CSR name: %s", cfg.CSRName)
+					return nil, fmt.Errorf("%w for this pod. CSR name: %s", ErrCSRFailed, cfg.CSRName)
 				}
 			}
 			if approved {
-				return WriteCertificateToFile(cfg, chcsr.Status.Certificate, x509CSR)
+				return chcsr.Status.Certificate, nil
 			}
 		}
 	}
-	return nil
+	return nil, nil
 }
 
 // WriteCertificateToFile writes TLS key, cert and cacert to the mount location specified in the config parameter.
+// Each file is written to a temporary name in the same directory and renamed into place, so that
+// a consumer reading the mount never observes a half-written cert or key, even on renewal.
 func WriteCertificateToFile(cfg *cfg.Config, cert []byte, x509CSR *tls.X509CSR) error {
 	log.Infof("the CSR has been signed and approved, writing to certificate location: %v", cfg.EmptyDirLocation)
 
 	// Give other users read permission to this file.
-	err := os.WriteFile(path.Join(cfg.EmptyDirLocation, cfg.CertName), cert, os.FileMode(0744))
+	err := writeFileAtomic(cfg.EmptyDirLocation, cfg.CertName, cert)
 	if err != nil {
 		return fmt.Errorf("error while writing to file: %w", err)
 	}
 
-	// Give other users read permission to this file.
-	err = os.WriteFile(path.Join(cfg.EmptyDirLocation, cfg.KeyName), x509CSR.PrivateKeyPEM, os.FileMode(0744))
-	if err != nil {
-		return fmt.Errorf("error while writing to file: %w", err)
+	// A hardware- or KMS-backed KeyProvider doesn't return key material at all (it never leaves
+	// the provider), so there's nothing to write here; the pod's consumer must be configured to
+	// reach the key through that provider directly.
+	if len(x509CSR.PrivateKeyPEM) > 0 {
+		// Give other users read permission to this file.
+		err = writeFileAtomic(cfg.EmptyDirLocation, cfg.KeyName, x509CSR.PrivateKeyPEM)
+		if err != nil {
+			return fmt.Errorf("error while writing to file: %w", err)
+		}
 	}
 
 	// Write the CA Cert to a file if it was provided.
 	if len(cfg.CACertPEM) > 0 && len(cfg.CACertName) > 0 {
-		err = os.WriteFile(path.Join(cfg.EmptyDirLocation, cfg.CACertName), cfg.CACertPEM, os.FileMode(0744))
+		err = writeFileAtomic(cfg.EmptyDirLocation, cfg.CACertName, cfg.CACertPEM)
 		if err != nil {
 			return fmt.Errorf("error while writing to file: %w", err)
 		}
@@ -156,6 +161,21 @@ func WriteCertificateToFile(cfg *cfg.Config, cert []byte, x509CSR *tls.X509CSR)
 	return nil
 }
 
+// pqSignatureAnnotation carries a hybrid CSR's ML-DSA-65 companion signature (tls.X509CSR.PQSignature)
+// on the submitted CertificateSigningRequest object, since it can't be embedded in the CSR's own
+// DER bytes without invalidating the classical signature computed over them. A CA that understands
+// hybrid mode can check it alongside the CSR's classical signature; one that doesn't can ignore it.
+const pqSignatureAnnotation = "key-cert-provisioner.tigera.io/mldsa65-signature"
+
+// pqSignatureAnnotations returns the ObjectMeta.Annotations for x509CSR, carrying its ML-DSA-65
+// companion signature when it has one. Returns nil for a CSR built from a non-hybrid key.
+func pqSignatureAnnotations(x509CSR *tls.X509CSR) map[string]string {
+	if len(x509CSR.PQSignature) == 0 {
+		return nil
+	}
+	return map[string]string{pqSignatureAnnotation: base64.StdEncoding.EncodeToString(x509CSR.PQSignature)}
+}
+
 // SubmitCSR Submits a CSR in order to obtain a signed certificate for this pod.
 func SubmitCSR(ctx context.Context, config *cfg.Config, restClient *RestClient, x509CSR *tls.X509CSR) error {
 	version, err := GetKubernetesVersion(restClient.Clientset)
@@ -181,7 +201,9 @@ func submitCSRUsingCertV1(ctx context.Context, config *cfg.Config, restClient *R
 			Name: config.CSRName,
 			Labels: map[string]string{
 				"k8s-app": config.AppName,
-			}},
+			},
+			Annotations: pqSignatureAnnotations(x509CSR),
+		},
 		Spec: certV1.CertificateSigningRequestSpec{
 			Request:    x509CSR.CSR,
 			SignerName: config.Signer,
@@ -217,7 +239,9 @@ func submitCSRUsingCertV1beta1(ctx context.Context, config *cfg.Config, restClie
 			Name: config.CSRName,
 			Labels: map[string]string{
 				"k8s-app": config.AppName,
-			}},
+			},
+			Annotations: pqSignatureAnnotations(x509CSR),
+		},
 		Spec: certV1beta1.CertificateSigningRequestSpec{
 			Request:    x509CSR.CSR,
 			SignerName: &config.Signer,
@@ -245,6 +269,47 @@ func submitCSRUsingCertV1beta1(ctx context.Context, config *cfg.Config, restClie
 	return nil
 }
 
+// RecordCSRFailure records a CSR that never produced a certificate against pkg/metrics,
+// distinguishing an explicit denial from any other failure.
+func RecordCSRFailure(signer string, err error) {
+	if stderrors.Is(err, ErrCSRDenied) {
+		metrics.RecordDenied(signer)
+		return
+	}
+	metrics.RecordFailed(signer)
+}
+
+// RecordCSRApproval records a CSR that was approved and written to disk at submittedAt against
+// pkg/metrics, including the written certificate's expiry.
+func RecordCSRApproval(config *cfg.Config, submittedAt time.Time) {
+	cert, err := ReadCertificate(config)
+	if err != nil {
+		log.WithError(err).Warn("unable to read written certificate for metrics")
+		metrics.RecordApproved(config.Signer, submittedAt, config.CertName, time.Time{})
+		return
+	}
+	metrics.RecordApproved(config.Signer, submittedAt, config.CertName, cert.NotAfter)
+}
+
+// ReadCertificate parses the certificate most recently written to
+// cfg.EmptyDirLocation/cfg.CertName.
+func ReadCertificate(config *cfg.Config) (*x509.Certificate, error) {
+	certPEM, err := os.ReadFile(path.Join(config.EmptyDirLocation, config.CertName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read written certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, fmt.Errorf("unable to decode certificate PEM at %s", config.CertName)
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse certificate: %w", err)
+	}
+	return cert, nil
+}
+
 func GetKubernetesVersion(clientset kubernetes.Interface) (*versionInfo, error) {
 	v, err := clientset.Discovery().ServerVersion()
 	if err != nil {
@@ -267,3 +332,26 @@ func GetKubernetesVersion(clientset kubernetes.Interface) (*versionInfo, error)
 		Minor: minor,
 	}, nil
 }
+
+// writeFileAtomic writes data to dir/name by first writing to a temporary file in dir and then
+// renaming it into place, so that a concurrent reader never sees a partial write.
+func writeFileAtomic(dir, name string, data []byte) error {
+	tmp, err := os.CreateTemp(dir, "."+name+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(os.FileMode(0744)); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path.Join(dir, name))
+}
@@ -16,6 +16,7 @@ package k8s_test
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"strconv"
 
@@ -89,6 +90,24 @@ var _ = Describe("Test Certificates", func() {
 			Expect(csr.Spec.SignerName).To(Equal(signer))
 			Expect(csr.Spec.Usages).To(ConsistOf(certV1.UsageServerAuth, certV1.UsageClientAuth, certV1.UsageDigitalSignature, certV1.UsageKeyAgreement))
 		})
+
+		It("should not set the ML-DSA-65 annotation for a CSR built from a classical key", func() {
+			Expect(k8s.SubmitCSR(ctx, config, restClient, tlsCsr)).ToNot(HaveOccurred())
+
+			csrs, err := clientset.CertificatesV1().CertificateSigningRequests().List(ctx, v1.ListOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(csrs.Items[0].Annotations).To(BeEmpty())
+		})
+
+		It("should carry a hybrid CSR's ML-DSA-65 companion signature as an annotation", func() {
+			hybridCsr := &tls.X509CSR{CSR: csrPem, PQSignature: []byte("<pq signature>")}
+			Expect(k8s.SubmitCSR(ctx, config, restClient, hybridCsr)).ToNot(HaveOccurred())
+
+			csrs, err := clientset.CertificatesV1().CertificateSigningRequests().List(ctx, v1.ListOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(csrs.Items[0].Annotations).To(HaveKeyWithValue(
+				"key-cert-provisioner.tigera.io/mldsa65-signature", base64.StdEncoding.EncodeToString(hybridCsr.PQSignature)))
+		})
 	})
 })
 
@@ -0,0 +1,145 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+
+	cmapi "github.com/cert-manager/cert-manager/pkg/apis/certmanager/v1"
+	cmmeta "github.com/cert-manager/cert-manager/pkg/apis/meta/v1"
+	cmclient "github.com/cert-manager/cert-manager/pkg/client/clientset/versioned"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// certManagerIssuer drives a cert-manager.io/v1 CertificateRequest instead of a Kubernetes
+// CertificateSigningRequest, so that clusters that manage PKI through cert-manager (ACME, Vault,
+// a private CA, ...) can issue this pod's certificate the same way they issue every other one.
+type certManagerIssuer struct {
+	config     *cfg.Config
+	restClient *RestClient
+}
+
+func (i *certManagerIssuer) cmClient() (cmclient.Interface, error) {
+	cs, err := cmclient.NewForConfig(i.restClient.RestConfig)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create cert-manager client: %w", err)
+	}
+	return cs, nil
+}
+
+// buildCertificateRequest constructs the cert-manager CertificateRequest x509CSR should be
+// submitted as, defaulting IssuerGroup to "cert-manager.io" (cert-manager's own built-in issuers)
+// when config doesn't target an external issuer.
+func buildCertificateRequest(config *cfg.Config, x509CSR *tls.X509CSR) *cmapi.CertificateRequest {
+	issuerGroup := config.IssuerGroup
+	if issuerGroup == "" {
+		issuerGroup = "cert-manager.io"
+	}
+
+	cr := &cmapi.CertificateRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        config.CSRName,
+			Namespace:   config.IssuerNamespace,
+			Annotations: pqSignatureAnnotations(x509CSR),
+		},
+		Spec: cmapi.CertificateRequestSpec{
+			Request: x509CSR.CSR,
+			IsCA:    false,
+			Usages:  []cmapi.KeyUsage{cmapi.UsageServerAuth, cmapi.UsageClientAuth, cmapi.UsageDigitalSignature, cmapi.UsageKeyAgreement},
+			IssuerRef: cmmeta.ObjectReference{
+				Name:  config.IssuerRef,
+				Kind:  config.IssuerKind,
+				Group: issuerGroup,
+			},
+		},
+	}
+	if config.Duration > 0 {
+		cr.Spec.Duration = &metav1.Duration{Duration: config.Duration}
+	}
+	return cr
+}
+
+func (i *certManagerIssuer) Submit(ctx context.Context, x509CSR *tls.X509CSR) (string, error) {
+	cs, err := i.cmClient()
+	if err != nil {
+		return "", err
+	}
+
+	namespace := i.config.IssuerNamespace
+	cr := buildCertificateRequest(i.config, x509CSR)
+
+	cli := cs.CertmanagerV1().CertificateRequests(namespace)
+	if _, err := cli.Create(ctx, cr, metav1.CreateOptions{}); err != nil {
+		if errors.IsAlreadyExists(err) {
+			// A previous run of this pod may have crashed after submitting. Delete and
+			// re-submit so we don't end up with a private key that doesn't match the request.
+			if err := cli.Delete(ctx, i.config.CSRName, metav1.DeleteOptions{}); err != nil {
+				return "", err
+			}
+			if _, err := cli.Create(ctx, cr, metav1.CreateOptions{}); err != nil {
+				return "", fmt.Errorf("unable to re-create CertificateRequest: %w", err)
+			}
+		} else {
+			return "", fmt.Errorf("unable to create CertificateRequest: %w", err)
+		}
+	}
+
+	log.Infof("created CertificateRequest: %v", i.config.CSRName)
+	return i.config.CSRName, nil
+}
+
+func (i *certManagerIssuer) Wait(ctx context.Context, reqID string) ([]byte, []byte, error) {
+	cs, err := i.cmClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cli := cs.CertmanagerV1().CertificateRequests(i.config.IssuerNamespace)
+	watcher, err := cli.Watch(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to watch CertificateRequests: %w", err)
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		cr, ok := event.Object.(*cmapi.CertificateRequest)
+		if !ok {
+			return nil, nil, fmt.Errorf("unexpected type in CertificateRequest channel: %T", event.Object)
+		}
+		if cr.Name != reqID {
+			continue
+		}
+		for _, c := range cr.Status.Conditions {
+			if c.Type == cmapi.CertificateRequestConditionReady && c.Status == cmmeta.ConditionTrue &&
+				len(cr.Status.Certificate) > 0 {
+				return cr.Status.Certificate, cr.Status.CA, nil
+			}
+			if c.Type == cmapi.CertificateRequestConditionReady && c.Status == cmmeta.ConditionFalse &&
+				c.Reason == cmapi.CertificateRequestReasonDenied {
+				return nil, nil, fmt.Errorf("%w: CertificateRequest %s", ErrCSRDenied, reqID)
+			}
+			if c.Type == cmapi.CertificateRequestConditionInvalidRequest && c.Status == cmmeta.ConditionTrue {
+				return nil, nil, fmt.Errorf("CertificateRequest is invalid: %s", reqID)
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("CertificateRequest watch channel closed before certificate was issued: %s", reqID)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"encoding/base64"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// buildCertificateRequest is exercised directly, rather than through certManagerIssuer.Submit,
+// because Submit builds its own cert-manager clientset from a rest.Config that this repo has no
+// fake for.
+var _ = Describe("Test buildCertificateRequest", func() {
+	x509CSR := &tls.X509CSR{CSR: []byte("<csr>")}
+
+	It("defaults IssuerGroup to cert-manager.io when unset", func() {
+		cr := buildCertificateRequest(&cfg.Config{CSRName: "ns:pod"}, x509CSR)
+		Expect(cr.Spec.IssuerRef.Group).To(Equal("cert-manager.io"))
+	})
+
+	It("uses the configured IssuerGroup for an external issuer", func() {
+		cr := buildCertificateRequest(&cfg.Config{IssuerGroup: "cert-manager.example.com"}, x509CSR)
+		Expect(cr.Spec.IssuerRef.Group).To(Equal("cert-manager.example.com"))
+	})
+
+	It("leaves Duration unset when the config requests the issuer's default", func() {
+		cr := buildCertificateRequest(&cfg.Config{}, x509CSR)
+		Expect(cr.Spec.Duration).To(BeNil())
+	})
+
+	It("sets Duration when the config requests a non-default validity period", func() {
+		cr := buildCertificateRequest(&cfg.Config{Duration: 90 * 24 * time.Hour}, x509CSR)
+		Expect(cr.Spec.Duration).To(Equal(&metav1.Duration{Duration: 90 * 24 * time.Hour}))
+	})
+
+	It("does not set the ML-DSA-65 annotation for a CertificateRequest built from a classical key", func() {
+		cr := buildCertificateRequest(&cfg.Config{}, x509CSR)
+		Expect(cr.Annotations).To(BeEmpty())
+	})
+
+	It("carries a hybrid CSR's ML-DSA-65 companion signature as an annotation", func() {
+		hybridCSR := &tls.X509CSR{CSR: []byte("<csr>"), PQSignature: []byte("<pq signature>")}
+		cr := buildCertificateRequest(&cfg.Config{}, hybridCSR)
+		Expect(cr.Annotations).To(HaveKeyWithValue(
+			"key-cert-provisioner.tigera.io/mldsa65-signature", base64.StdEncoding.EncodeToString(hybridCSR.PQSignature)))
+	})
+})
@@ -0,0 +1,136 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/metrics"
+	"github.com/tigera/key-cert-provisioner/pkg/reload"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+)
+
+// Issuer turns a CSR into a signed certificate. Implementations hide the mechanics of a
+// particular backend (the Kubernetes CertificateSigningRequest API, cert-manager, Vault, ...)
+// behind a common submit/wait flow so the rest of the program doesn't need to know which
+// backend issued the pod's certificate.
+type Issuer interface {
+	// Submit hands the CSR to the backend and returns an identifier that Wait can later use to
+	// look up the result (e.g. the CSR/CertificateRequest name).
+	Submit(ctx context.Context, x509CSR *tls.X509CSR) (reqID string, err error)
+	// Wait blocks until the request identified by reqID has been signed, returning the issued
+	// certificate and, if the backend supplies one, the CA bundle that signed it.
+	Wait(ctx context.Context, reqID string) (certPEM []byte, caPEM []byte, err error)
+}
+
+// NewIssuer returns the Issuer implementation selected by config.IssuerBackend.
+func NewIssuer(config *cfg.Config, restClient *RestClient) (Issuer, error) {
+	switch config.IssuerBackend {
+	case cfg.IssuerBackendKubeCSR, "":
+		return &kubeCSRIssuer{config: config, restClient: restClient}, nil
+	case cfg.IssuerBackendCertManager:
+		return &certManagerIssuer{config: config, restClient: restClient}, nil
+	case cfg.IssuerBackendVault:
+		return &vaultIssuer{config: config}, nil
+	default:
+		return nil, fmt.Errorf("unknown issuer backend: %q", config.IssuerBackend)
+	}
+}
+
+// IssueCertificate submits x509CSR to the issuer backend selected by config.IssuerBackend, waits
+// for it to be signed, and writes the result (including any CA bundle the backend supplies) to
+// config.EmptyDirLocation. It is the single path both the one-shot and daemon run modes use to
+// obtain a certificate, so every backend (kube-csr, cert-manager, Vault) gets the same Secret
+// output, CA-bundle injection, metrics and reload-notification behavior regardless of which one
+// is configured.
+func IssueCertificate(ctx context.Context, config *cfg.Config, restClient *RestClient, x509CSR *tls.X509CSR) error {
+	issuer, err := NewIssuer(config, restClient)
+	if err != nil {
+		return err
+	}
+
+	submittedAt := time.Now()
+	reqID, err := issuer.Submit(ctx, x509CSR)
+	if err != nil {
+		metrics.RecordFailed(config.Signer)
+		return fmt.Errorf("unable to submit certificate request: %w", err)
+	}
+	metrics.RecordSubmitted(config.Signer)
+
+	certPEM, caPEM, err := issuer.Wait(ctx, reqID)
+	if err != nil {
+		RecordCSRFailure(config.Signer, err)
+		return fmt.Errorf("unable to wait for certificate request: %w", err)
+	}
+
+	if len(caPEM) > 0 {
+		config.CACertPEM = caPEM
+	}
+	if err := WriteCertificateToFile(config, certPEM, x509CSR); err != nil {
+		return err
+	}
+	if config.SecretOutput {
+		if err := WriteCertificateToSecret(ctx, config, restClient, certPEM, x509CSR); err != nil {
+			return err
+		}
+	}
+	if len(config.CAInjectionTargets) > 0 {
+		targets, err := ParseCAInjectionTargets(config.CAInjectionTargets)
+		if err != nil {
+			return err
+		}
+		if err := InjectCABundle(ctx, restClient, targets, config.CACertPEM); err != nil {
+			return err
+		}
+	}
+
+	RecordCSRApproval(config, submittedAt)
+	reload.Notify(config)
+	return nil
+}
+
+// kubeCSRIssuer adapts the existing certificates.k8s.io CSR flow to the Issuer interface.
+type kubeCSRIssuer struct {
+	config     *cfg.Config
+	restClient *RestClient
+}
+
+func (i *kubeCSRIssuer) Submit(ctx context.Context, x509CSR *tls.X509CSR) (string, error) {
+	if err := SubmitCSR(ctx, i.config, i.restClient, x509CSR); err != nil {
+		return "", err
+	}
+	return i.config.CSRName, nil
+}
+
+func (i *kubeCSRIssuer) Wait(ctx context.Context, reqID string) ([]byte, []byte, error) {
+	version, err := GetKubernetesVersion(i.restClient.Clientset)
+	if err != nil {
+		return nil, nil, err
+	}
+	watcher, err := createCSRWatcher(ctx, i.restClient, version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to watch certificate requests: %w", err)
+	}
+	log.Infof("watching CSR until it has been signed and approved: %v", i.config.CSRName)
+	cert, err := watchCSRBasedOnKubernetesVersion(watcher, i.config, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, nil, nil
+}
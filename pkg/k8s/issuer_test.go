@@ -0,0 +1,49 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+// These dispatch checks live in an internal (package k8s) test file, rather than alongside the
+// other k8s_test specs, because kubeCSRIssuer/certManagerIssuer/vaultIssuer are unexported.
+var _ = Describe("Test issuer backend selection", func() {
+	It("defaults to the Kubernetes CSR issuer when IssuerBackend is unset", func() {
+		issuer, err := NewIssuer(&cfg.Config{}, &RestClient{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issuer).To(BeAssignableToTypeOf(&kubeCSRIssuer{}))
+	})
+
+	It("selects the cert-manager issuer for IssuerBackendCertManager", func() {
+		issuer, err := NewIssuer(&cfg.Config{IssuerBackend: cfg.IssuerBackendCertManager}, &RestClient{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issuer).To(BeAssignableToTypeOf(&certManagerIssuer{}))
+	})
+
+	It("selects the Vault issuer for IssuerBackendVault", func() {
+		issuer, err := NewIssuer(&cfg.Config{IssuerBackend: cfg.IssuerBackendVault}, &RestClient{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issuer).To(BeAssignableToTypeOf(&vaultIssuer{}))
+	})
+
+	It("errors for an unknown backend", func() {
+		_, err := NewIssuer(&cfg.Config{IssuerBackend: "bogus"}, &RestClient{})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,162 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/metrics"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+)
+
+// RunRenewalLoop obtains an initial certificate and then, in cfg.Config.Mode ModeDaemon, stays
+// resident and renews it as it approaches expiry instead of returning. The renewal point is
+// cfg.Config.RenewBefore before the certificate's expiry when set, falling back to
+// cfg.Config.RenewBeforeRatio of its lifetime otherwise. Each renewal submits a fresh CSR under a
+// rotated CSRName so it doesn't collide with the previous, already-approved-or-denied object, and
+// atomically replaces the files written by the previous certificate once it's signed. With the
+// software key provider each CSR carries a newly generated private key; the PKCS#11 and KMS
+// providers keep signing with their existing key.
+//
+// Outside ModeDaemon, RunRenewalLoop returns once the initial certificate is written. In
+// ModeDaemon it only returns when ctx is done, the initial certificate can't be obtained, a CSR
+// can't be created, the written certificate can't be read back to schedule its renewal, or a
+// renewal fails metrics.MaxConsecutiveFailures attempts in a row; any of those is treated as
+// unrecoverable so that the existing crash-loop semantics in cmd/main.go still apply. A renewal
+// that fails fewer times than that is retried with backoff rather than crashing the pod, since its
+// current, still-valid certificate means there's no urgency to restart.
+func RunRenewalLoop(ctx context.Context, config *cfg.Config, restClient *RestClient) error {
+	baseCSRName := config.CSRName
+
+	for iteration := 0; ; iteration++ {
+		if iteration > 0 {
+			config.CSRName = fmt.Sprintf("%s-%d", baseCSRName, time.Now().UnixNano())
+		}
+
+		x509CSR, err := tls.CreateX509CSR(config)
+		if err != nil {
+			return fmt.Errorf("unable to create x509 certificate request: %w", err)
+		}
+
+		if iteration == 0 {
+			// The very first certificate gates pod startup: if this pod can't get an initial
+			// cert there's nothing useful for it to keep doing, so fail fast and let it
+			// crash-loop.
+			if err := IssueCertificate(ctx, config, restClient, x509CSR); err != nil {
+				return err
+			}
+		} else if err := issueWithRetry(ctx, config, restClient, x509CSR); err != nil {
+			return err
+		}
+
+		if config.Mode != cfg.ModeDaemon {
+			return nil
+		}
+
+		renewAt, err := renewalTime(config)
+		if err != nil {
+			return err
+		}
+		log.Infof("certificate written, next renewal at: %v", renewAt)
+
+		if err := sleepUntil(ctx, renewAt, config.RenewalCheckInterval); err != nil {
+			return err
+		}
+	}
+}
+
+// issueWithRetry calls IssueCertificate up to metrics.MaxConsecutiveFailures times in total, with
+// exponential backoff between attempts, before giving up. This bounds renewal attempts to the same
+// threshold /readyz uses to flip back to not-ready, so when every attempt fails while submitting
+// or waiting for the request (the failures IssueCertificate records in pkg/metrics), a pod that's
+// about to crash-loop has already told its readiness probe it's unhealthy. Attempts that fail
+// after the certificate was issued (writing the files or Secret, or injecting the CA bundle) are
+// not recorded as failures and do not count toward that threshold. A pod that recovers within
+// that many attempts never needed to restart at all.
+func issueWithRetry(ctx context.Context, config *cfg.Config, restClient *RestClient, x509CSR *tls.X509CSR) error {
+	backoff := time.Second
+	var err error
+	for attempt := 1; attempt <= metrics.MaxConsecutiveFailures; attempt++ {
+		if err = IssueCertificate(ctx, config, restClient, x509CSR); err == nil {
+			return nil
+		}
+		log.WithError(err).Warnf("renewal attempt %d/%d failed", attempt, metrics.MaxConsecutiveFailures)
+		if attempt == metrics.MaxConsecutiveFailures {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return fmt.Errorf("renewal failed after %d attempts: %w", metrics.MaxConsecutiveFailures, err)
+}
+
+// sleepUntil blocks until deadline, ctx is done, or an error occurs, waking up at most every
+// checkInterval so that a pod whose clock jumps or that was suspended doesn't sleep through its
+// renewal point.
+func sleepUntil(ctx context.Context, deadline time.Time, checkInterval time.Duration) error {
+	if checkInterval <= 0 {
+		checkInterval = time.Hour
+	}
+	for {
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil
+		}
+		if wait > checkInterval {
+			wait = checkInterval
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// renewalTime reads the certificate just written to cfg.EmptyDirLocation/cfg.CertName and
+// returns the time at which it should be renewed: RenewBefore before expiry when set, otherwise
+// once RenewBeforeRatio of its validity period has elapsed.
+func renewalTime(config *cfg.Config) (time.Time, error) {
+	cert, err := ReadCertificate(config)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var renewAt time.Time
+	if config.RenewBefore > 0 {
+		renewAt = cert.NotAfter.Add(-config.RenewBefore)
+	} else {
+		renewBeforeRatio := config.RenewBeforeRatio
+		if renewBeforeRatio <= 0 || renewBeforeRatio >= 1 {
+			renewBeforeRatio = 2.0 / 3.0
+		}
+		lifetime := cert.NotAfter.Sub(cert.NotBefore)
+		renewAt = cert.NotBefore.Add(time.Duration(float64(lifetime) * renewBeforeRatio))
+	}
+
+	// Jitter the renewal point by up to a minute so that many pods renewing the same signer
+	// at once don't all hammer the API server in the same instant.
+	renewAt = renewAt.Add(time.Duration(rand.Int63n(int64(time.Minute))))
+	return renewAt, nil
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// WriteCertificateToSecret creates or updates a kubernetes.io/tls Secret with the issued
+// certificate, so that controllers which consume Secrets (Ingress, admission webhook
+// configurations, ...) rather than emptyDir volumes can use the material this pod requested.
+// The Secret's ownerReferences point at the owning pod so it is garbage collected with it.
+func WriteCertificateToSecret(ctx context.Context, config *cfg.Config, restClient *RestClient, cert []byte, x509CSR *tls.X509CSR) error {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      config.SecretName,
+			Namespace: config.SecretNamespace,
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Pod",
+					Name:       config.OwnerPodName,
+					UID:        types.UID(config.OwnerPodUID),
+				},
+			},
+		},
+		Type: v1.SecretTypeTLS,
+		Data: map[string][]byte{
+			v1.TLSCertKey:       cert,
+			v1.TLSPrivateKeyKey: x509CSR.PrivateKeyPEM,
+		},
+	}
+	if len(config.CACertPEM) > 0 {
+		secret.Data["ca.crt"] = config.CACertPEM
+	}
+
+	cli := restClient.Clientset.CoreV1().Secrets(config.SecretNamespace)
+	existing, err := cli.Get(ctx, config.SecretName, metav1.GetOptions{})
+	if err == nil {
+		existing.OwnerReferences = secret.OwnerReferences
+		existing.Type = secret.Type
+		existing.Data = secret.Data
+		_, err = cli.Update(ctx, existing, metav1.UpdateOptions{})
+	} else if errors.IsNotFound(err) {
+		_, err = cli.Create(ctx, secret, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("unable to write certificate to secret %s/%s: %w", config.SecretNamespace, config.SecretName, err)
+	}
+
+	log.Infof("wrote certificate to secret: %s/%s", config.SecretNamespace, config.SecretName)
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+)
+
+// pqSignatureHeader carries a hybrid CSR's ML-DSA-65 companion signature to Vault the same way
+// pqSignatureAnnotation carries it to the Kubernetes CSR and cert-manager CertificateRequest APIs:
+// Vault's sign endpoint only accepts a CSR and a handful of known fields, so there's nowhere in
+// the request body for it to live.
+const pqSignatureHeader = "X-Pq-Signature"
+
+// vaultIssuer drives the Vault PKI secrets engine's sign endpoint directly, sending the contents
+// of the projected service-account token file at VaultTokenPath as the X-Vault-Token header. It
+// does not log in through a Vault auth method to exchange that token for a Vault token.
+//
+// Unlike the other backends, Vault signs synchronously: Submit performs the sign call and Wait
+// simply hands back the already-issued material.
+type vaultIssuer struct {
+	config *cfg.Config
+
+	certPEM []byte
+	caPEM   []byte
+}
+
+type vaultSignRequest struct {
+	CSR        string `json:"csr"`
+	CommonName string `json:"common_name"`
+}
+
+type vaultSignResponse struct {
+	Data struct {
+		Certificate string `json:"certificate"`
+		IssuingCA   string `json:"issuing_ca"`
+	} `json:"data"`
+	Errors []string `json:"errors"`
+}
+
+func (i *vaultIssuer) Submit(ctx context.Context, x509CSR *tls.X509CSR) (string, error) {
+	token, err := os.ReadFile(i.config.VaultTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read vault service-account token: %w", err)
+	}
+
+	reqBody, err := json.Marshal(vaultSignRequest{CSR: string(x509CSR.CSR), CommonName: i.config.CommonName})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal vault sign request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s/sign/%s", i.config.VaultAddr, i.config.VaultPKIMount, i.config.VaultPKIRole)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return "", fmt.Errorf("unable to build vault sign request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", string(bytes.TrimSpace(token)))
+	req.Header.Set("Content-Type", "application/json")
+	if len(x509CSR.PQSignature) > 0 {
+		req.Header.Set(pqSignatureHeader, base64.StdEncoding.EncodeToString(x509CSR.PQSignature))
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var signResp vaultSignResponse
+	if err := json.NewDecoder(resp.Body).Decode(&signResp); err != nil {
+		return "", fmt.Errorf("unable to decode vault sign response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d signing CSR: %v", resp.StatusCode, signResp.Errors)
+	}
+
+	i.certPEM = []byte(signResp.Data.Certificate)
+	i.caPEM = []byte(signResp.Data.IssuingCA)
+	return i.config.CSRName, nil
+}
+
+func (i *vaultIssuer) Wait(ctx context.Context, reqID string) ([]byte, []byte, error) {
+	if len(i.certPEM) == 0 {
+		return nil, nil, fmt.Errorf("vault issuer has no certificate for request: %s", reqID)
+	}
+	return i.certPEM, i.caPEM, nil
+}
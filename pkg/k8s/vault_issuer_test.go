@@ -0,0 +1,96 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package k8s
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+)
+
+var _ = Describe("Test vaultIssuer.Submit", func() {
+	var (
+		dir          string
+		tokenPath    string
+		lastReq      *http.Request
+		lastPQHeader string
+		server       *httptest.Server
+	)
+
+	BeforeEach(func() {
+		lastReq = nil
+		lastPQHeader = ""
+
+		var err error
+		dir, err = os.MkdirTemp("", "vault-issuer-test")
+		Expect(err).NotTo(HaveOccurred())
+		tokenPath = filepath.Join(dir, "token")
+		Expect(os.WriteFile(tokenPath, []byte("vault-token"), 0600)).To(Succeed())
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lastReq = r
+			lastPQHeader = r.Header.Get(pqSignatureHeader)
+			w.WriteHeader(http.StatusOK)
+			_ = json.NewEncoder(w).Encode(vaultSignResponse{
+				Data: struct {
+					Certificate string `json:"certificate"`
+					IssuingCA   string `json:"issuing_ca"`
+				}{Certificate: "<cert>", IssuingCA: "<ca>"},
+			})
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	newIssuer := func() *vaultIssuer {
+		return &vaultIssuer{config: &cfg.Config{
+			VaultTokenPath: tokenPath,
+			VaultAddr:      server.URL,
+			VaultPKIMount:  "pki",
+			VaultPKIRole:   "role",
+			CommonName:     "example.com",
+			CSRName:        "ns:pod",
+		}}
+	}
+
+	It("does not set the ML-DSA-65 signature header for a CSR built from a classical key", func() {
+		i := newIssuer()
+		_, err := i.Submit(context.Background(), &tls.X509CSR{CSR: []byte("<csr>")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastReq).NotTo(BeNil())
+		Expect(lastPQHeader).To(BeEmpty())
+	})
+
+	It("carries a hybrid CSR's ML-DSA-65 companion signature as a request header", func() {
+		i := newIssuer()
+		pqSig := []byte("<pq signature>")
+		_, err := i.Submit(context.Background(), &tls.X509CSR{CSR: []byte("<csr>"), PQSignature: pqSig})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lastPQHeader).To(Equal(base64.StdEncoding.EncodeToString(pqSig)))
+	})
+})
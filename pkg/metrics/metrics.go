@@ -0,0 +1,166 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics exposes Prometheus metrics and a health/readiness endpoint for cluster
+// monitoring to alarm on CSR failures or impending certificate expiry, e.g. via the ARO-style
+// certificate-expiration monitor pattern.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	csrSubmitted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "key_cert_provisioner_csr_submitted_total",
+		Help: "Number of certificate signing requests submitted, by signer.",
+	}, []string{"signer"})
+
+	csrApproved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "key_cert_provisioner_csr_approved_total",
+		Help: "Number of certificate signing requests approved, by signer.",
+	}, []string{"signer"})
+
+	csrDenied = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "key_cert_provisioner_csr_denied_total",
+		Help: "Number of certificate signing requests denied, by signer.",
+	}, []string{"signer"})
+
+	csrFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "key_cert_provisioner_csr_failed_total",
+		Help: "Number of certificate signing requests that failed for a reason other than an explicit denial, by signer.",
+	}, []string{"signer"})
+
+	approvalLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "key_cert_provisioner_csr_approval_latency_seconds",
+		Help:    "Time between submitting a CSR and it being approved and written to disk, by signer.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"signer"})
+
+	certNotAfter = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "cert_not_after_seconds",
+		Help: "Unix timestamp, in seconds, of the NotAfter field of the most recently written certificate, by output cert file.",
+	}, []string{"cert"})
+)
+
+// MaxConsecutiveFailures is how many consecutive CSR failures /readyz tolerates, in daemon-
+// rotation mode, before it flips back to not-ready. A single renewal hiccup shouldn't page
+// anyone while the still-valid previous certificate is in use. It's exported so that
+// pkg/k8s.RunRenewalLoop's retry-with-backoff bound matches the threshold /readyz actually acts
+// on, instead of the two drifting apart.
+const MaxConsecutiveFailures = 3
+
+var (
+	readyMu             sync.Mutex
+	ready               bool
+	consecutiveFailures int
+)
+
+// RecordSubmitted records that a CSR was submitted to the given signer.
+func RecordSubmitted(signer string) {
+	csrSubmitted.WithLabelValues(signer).Inc()
+}
+
+// RecordApproved records that a CSR submitted at submittedAt was approved, and that certName now
+// holds a certificate valid until notAfter. It marks the process ready and resets the consecutive
+// failure count used by /readyz.
+func RecordApproved(signer string, submittedAt time.Time, certName string, notAfter time.Time) {
+	csrApproved.WithLabelValues(signer).Inc()
+	approvalLatency.WithLabelValues(signer).Observe(time.Since(submittedAt).Seconds())
+	if !notAfter.IsZero() {
+		certNotAfter.WithLabelValues(certName).Set(float64(notAfter.Unix()))
+	}
+	setReady(true)
+}
+
+// RecordDenied records that a CSR was explicitly denied and counts it toward /readyz's failure
+// threshold.
+func RecordDenied(signer string) {
+	csrDenied.WithLabelValues(signer).Inc()
+	recordFailure()
+}
+
+// RecordFailed records that a CSR failed for a reason other than an explicit denial and counts it
+// toward /readyz's failure threshold.
+func RecordFailed(signer string) {
+	csrFailed.WithLabelValues(signer).Inc()
+	recordFailure()
+}
+
+func recordFailure() {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+	consecutiveFailures++
+	if consecutiveFailures >= MaxConsecutiveFailures {
+		ready = false
+	}
+}
+
+func setReady(isReady bool) {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+	ready = isReady
+	if isReady {
+		consecutiveFailures = 0
+	}
+}
+
+// IsReady reports whether /readyz currently reports ready.
+func IsReady() bool {
+	readyMu.Lock()
+	defer readyMu.Unlock()
+	return ready
+}
+
+// Serve starts an HTTP server on addr exposing /metrics, /healthz and /readyz, and blocks until
+// ctx is done. /healthz always reports ok once the process is up; /readyz reports ok only after
+// the first successful certificate write, and flips back to unavailable if renewals keep failing.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !IsReady() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	log.Infof("serving metrics and health checks on %s", addr)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return fmt.Errorf("metrics server failed: %w", err)
+	}
+	return nil
+}
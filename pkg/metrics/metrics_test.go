@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// These are internal (package metrics) tests, since setReady/recordFailure/consecutiveFailures
+// are unexported and ready/consecutiveFailures are shared, mutable package state; every spec
+// resets that state itself rather than relying on run order.
+var _ = Describe("Test readiness tracking", func() {
+	BeforeEach(func() {
+		setReady(false)
+	})
+
+	It("reports not ready until a certificate is approved", func() {
+		Expect(IsReady()).To(BeFalse())
+	})
+
+	It("reports ready after RecordApproved", func() {
+		RecordApproved("signer", time.Now(), "tls.crt", time.Now().Add(time.Hour))
+		Expect(IsReady()).To(BeTrue())
+	})
+
+	It("tolerates fewer than MaxConsecutiveFailures consecutive failures", func() {
+		RecordApproved("signer", time.Now(), "tls.crt", time.Now().Add(time.Hour))
+		for i := 0; i < MaxConsecutiveFailures-1; i++ {
+			RecordFailed("signer")
+		}
+		Expect(IsReady()).To(BeTrue())
+	})
+
+	It("flips to not-ready once MaxConsecutiveFailures is reached", func() {
+		RecordApproved("signer", time.Now(), "tls.crt", time.Now().Add(time.Hour))
+		for i := 0; i < MaxConsecutiveFailures; i++ {
+			RecordFailed("signer")
+		}
+		Expect(IsReady()).To(BeFalse())
+	})
+
+	It("resets the consecutive failure count on the next approval", func() {
+		RecordApproved("signer", time.Now(), "tls.crt", time.Now().Add(time.Hour))
+		for i := 0; i < MaxConsecutiveFailures-1; i++ {
+			RecordFailed("signer")
+		}
+		RecordApproved("signer", time.Now(), "tls.crt", time.Now().Add(time.Hour))
+		for i := 0; i < MaxConsecutiveFailures-1; i++ {
+			RecordFailed("signer")
+		}
+		Expect(IsReady()).To(BeTrue())
+	})
+
+	It("counts an explicit denial toward the same failure threshold as RecordFailed", func() {
+		RecordApproved("signer", time.Now(), "tls.crt", time.Now().Add(time.Hour))
+		for i := 0; i < MaxConsecutiveFailures; i++ {
+			RecordDenied("signer")
+		}
+		Expect(IsReady()).To(BeFalse())
+	})
+})
@@ -0,0 +1,107 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package reload notifies a consumer of the key/cert this module writes that new material is
+// available, either by signaling a running process or by exec'ing a user-supplied command.
+package reload
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+var signalNames = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+}
+
+// Notify runs the configured reload actions after a successful certificate write: signaling a
+// process (cfg.Config.ReloadSignalPID or ReloadSignalPIDFile) and/or exec'ing cfg.Config.ReloadCommand
+// with the key path, cert path and common name in its environment. Both are no-ops when
+// unconfigured. Errors are logged rather than returned, since a reload hook failing shouldn't be
+// treated the same as a failure to obtain the certificate itself.
+func Notify(config *cfg.Config) {
+	if err := signalProcess(config); err != nil {
+		log.WithError(err).Warn("unable to signal reload target")
+	}
+	if err := runReloadCommand(config); err != nil {
+		log.WithError(err).Warn("unable to run reload command")
+	}
+}
+
+func signalProcess(config *cfg.Config) error {
+	pid := config.ReloadSignalPID
+	if config.ReloadSignalPIDFile != "" {
+		contents, err := os.ReadFile(config.ReloadSignalPIDFile)
+		if err != nil {
+			return fmt.Errorf("unable to read reload signal pid file: %w", err)
+		}
+		pid, err = strconv.Atoi(strings.TrimSpace(string(contents)))
+		if err != nil {
+			return fmt.Errorf("reload signal pid file does not contain a valid pid: %w", err)
+		}
+	}
+	if pid == 0 {
+		return nil
+	}
+
+	sig := syscall.SIGHUP
+	if config.ReloadSignal != "" {
+		named, ok := signalNames[config.ReloadSignal]
+		if !ok {
+			return fmt.Errorf("unknown reload signal: %q", config.ReloadSignal)
+		}
+		sig = named
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("unable to find reload target process %d: %w", pid, err)
+	}
+	if err := process.Signal(sig); err != nil {
+		return fmt.Errorf("unable to signal reload target process %d: %w", pid, err)
+	}
+	log.Infof("sent %s to pid %d to reload certificate", sig, pid)
+	return nil
+}
+
+func runReloadCommand(config *cfg.Config) error {
+	if len(config.ReloadCommand) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command(config.ReloadCommand[0], config.ReloadCommand[1:]...)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("KEY_PATH=%s", path.Join(config.EmptyDirLocation, config.KeyName)),
+		fmt.Sprintf("CERT_PATH=%s", path.Join(config.EmptyDirLocation, config.CertName)),
+		fmt.Sprintf("COMMON_NAME=%s", config.CommonName),
+	)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("reload command %q failed: %w", config.ReloadCommand, err)
+	}
+	log.Infof("ran reload command: %v", config.ReloadCommand)
+	return nil
+}
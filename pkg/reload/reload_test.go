@@ -0,0 +1,117 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reload
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+// These are internal (package reload) tests, since signalProcess/runReloadCommand are unexported.
+var _ = Describe("Test signalProcess", func() {
+	It("is a no-op when neither ReloadSignalPID nor ReloadSignalPIDFile is set", func() {
+		Expect(signalProcess(&cfg.Config{})).To(Succeed())
+	})
+
+	It("errors when ReloadSignalPIDFile doesn't exist", func() {
+		err := signalProcess(&cfg.Config{ReloadSignalPIDFile: filepath.Join(os.TempDir(), "does-not-exist-pid-file")})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors when ReloadSignalPIDFile doesn't contain a valid pid", func() {
+		dir, err := os.MkdirTemp("", "reload-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		pidFile := filepath.Join(dir, "pid")
+		Expect(os.WriteFile(pidFile, []byte("not-a-pid"), 0644)).To(Succeed())
+
+		err = signalProcess(&cfg.Config{ReloadSignalPIDFile: pidFile})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors for an unknown ReloadSignal name", func() {
+		err := signalProcess(&cfg.Config{ReloadSignalPID: os.Getpid(), ReloadSignal: "SIGBOGUS"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("signals the configured pid with the configured signal", func() {
+		cmd := exec.Command("sleep", "30")
+		Expect(cmd.Start()).To(Succeed())
+		defer func() { _ = cmd.Process.Kill() }()
+
+		err := signalProcess(&cfg.Config{ReloadSignalPID: cmd.Process.Pid, ReloadSignal: "SIGUSR1"})
+		Expect(err).NotTo(HaveOccurred())
+
+		// SIGUSR1 has no handler installed in a bare "sleep", so its default action terminates
+		// the process; that's how this test confirms the signal was actually delivered.
+		Expect(cmd.Wait()).To(HaveOccurred())
+	})
+
+	It("reads the pid from ReloadSignalPIDFile when set", func() {
+		cmd := exec.Command("sleep", "30")
+		Expect(cmd.Start()).To(Succeed())
+		defer func() { _ = cmd.Process.Kill() }()
+
+		dir, err := os.MkdirTemp("", "reload-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		pidFile := filepath.Join(dir, "pid")
+		Expect(os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644)).To(Succeed())
+
+		Expect(signalProcess(&cfg.Config{ReloadSignalPIDFile: pidFile, ReloadSignal: "SIGUSR1"})).To(Succeed())
+		Expect(cmd.Wait()).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Test runReloadCommand", func() {
+	It("is a no-op when ReloadCommand is unset", func() {
+		Expect(runReloadCommand(&cfg.Config{})).To(Succeed())
+	})
+
+	It("runs the configured command with KEY_PATH/CERT_PATH/COMMON_NAME in its environment", func() {
+		dir, err := os.MkdirTemp("", "reload-test")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		outFile := filepath.Join(dir, "out")
+		config := &cfg.Config{
+			ReloadCommand:    []string{"sh", "-c", "printf '%s:%s:%s' \"$KEY_PATH\" \"$CERT_PATH\" \"$COMMON_NAME\" > " + outFile},
+			EmptyDirLocation: "/mnt/certs",
+			KeyName:          "tls.key",
+			CertName:         "tls.crt",
+			CommonName:       "example.com",
+		}
+
+		Expect(runReloadCommand(config)).To(Succeed())
+
+		out, err := os.ReadFile(outFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(out)).To(Equal("/mnt/certs/tls.key:/mnt/certs/tls.crt:example.com"))
+	})
+
+	It("errors when the command fails", func() {
+		err := runReloadCommand(&cfg.Config{ReloadCommand: []string{"sh", "-c", "exit 1"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
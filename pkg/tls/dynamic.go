@@ -0,0 +1,141 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apiserver/pkg/server/dynamiccertificates"
+)
+
+// DynamicFileCertKeyContentProvider implements dynamiccertificates.CertKeyContentProvider and
+// dynamiccertificates.Notifier over the cert/key files pkg/k8s.WriteCertificateToFile writes. An apiserver (or anything else) running in the same pod as this provisioner can embed
+// one of these to pick up a renewed certificate without restarting.
+//
+// Call Start to have it watch the emptyDir itself (a poll loop, since atomic file replacement
+// via rename doesn't produce a reliable inotify event on every filesystem emptyDir can be backed
+// by); CheckAndReload remains exported for callers that already have their own trigger, e.g. a
+// SIGHUP handler.
+type DynamicFileCertKeyContentProvider struct {
+	name     string
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert []byte
+	key  []byte
+
+	listenersMu sync.Mutex
+	listeners   []dynamiccertificates.Listener
+}
+
+var _ dynamiccertificates.CertKeyContentProvider = &DynamicFileCertKeyContentProvider{}
+var _ dynamiccertificates.Notifier = &DynamicFileCertKeyContentProvider{}
+
+// NewDynamicFileCertKeyContentProvider returns a provider backed by dir/certFileName and
+// dir/keyFileName, performing an initial load so CurrentCertKeyContent has something to return
+// before the first CheckAndReload.
+func NewDynamicFileCertKeyContentProvider(name, dir, certFileName, keyFileName string) (*DynamicFileCertKeyContentProvider, error) {
+	p := &DynamicFileCertKeyContentProvider{
+		name:     name,
+		certFile: path.Join(dir, certFileName),
+		keyFile:  path.Join(dir, keyFileName),
+	}
+	if _, err := p.CheckAndReload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Name implements dynamiccertificates.CertKeyContentProvider.
+func (p *DynamicFileCertKeyContentProvider) Name() string {
+	return p.name
+}
+
+// CurrentCertKeyContent implements dynamiccertificates.CertKeyContentProvider.
+func (p *DynamicFileCertKeyContentProvider) CurrentCertKeyContent() ([]byte, []byte) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cert, p.key
+}
+
+// AddListener implements dynamiccertificates.Notifier.
+func (p *DynamicFileCertKeyContentProvider) AddListener(listener dynamiccertificates.Listener) {
+	p.listenersMu.Lock()
+	defer p.listenersMu.Unlock()
+	p.listeners = append(p.listeners, listener)
+}
+
+// Start polls the cert/key files every interval, calling CheckAndReload on each tick, until ctx
+// is done. Reload errors (e.g. a torn write caught mid-replacement) are logged and retried on the
+// next tick rather than treated as fatal, since the files are expected to settle by then.
+func (p *DynamicFileCertKeyContentProvider) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.CheckAndReload(); err != nil {
+				log.Warnf("%s: failed to reload certificate and key from %s/%s: %v", p.name, p.certFile, p.keyFile, err)
+			}
+		}
+	}
+}
+
+// CheckAndReload re-reads the cert/key files, validates they form a matching pair, and, if their
+// content changed since the last call, swaps them in and notifies every registered listener. It
+// returns whether the content changed.
+func (p *DynamicFileCertKeyContentProvider) CheckAndReload() (bool, error) {
+	cert, err := os.ReadFile(p.certFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to read certificate file %s: %w", p.certFile, err)
+	}
+	key, err := os.ReadFile(p.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to read key file %s: %w", p.keyFile, err)
+	}
+
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return false, fmt.Errorf("certificate and key at %s/%s do not form a valid pair: %w", p.certFile, p.keyFile, err)
+	}
+
+	p.mu.Lock()
+	changed := string(cert) != string(p.cert) || string(key) != string(p.key)
+	if changed {
+		p.cert = cert
+		p.key = key
+	}
+	p.mu.Unlock()
+
+	if changed {
+		log.Infof("%s: reloaded certificate and key from %s/%s", p.name, p.certFile, p.keyFile)
+		p.listenersMu.Lock()
+		listeners := append([]dynamiccertificates.Listener{}, p.listeners...)
+		p.listenersMu.Unlock()
+		for _, listener := range listeners {
+			listener.Enqueue()
+		}
+	}
+	return changed, nil
+}
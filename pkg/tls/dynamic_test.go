@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/tls"
+)
+
+// selfSignedPair returns a freshly generated, PEM-encoded self-signed cert/key pair, distinct on
+// every call (each uses its own key and serial number) so tests can tell two pairs apart.
+func selfSignedPair(commonName string) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).NotTo(HaveOccurred())
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+// countingListener implements dynamiccertificates.Listener, recording how many times it was
+// notified.
+type countingListener struct {
+	count int32
+}
+
+func (l *countingListener) Enqueue() {
+	atomic.AddInt32(&l.count, 1)
+}
+
+var _ = Describe("Test DynamicFileCertKeyContentProvider", func() {
+	var (
+		dir             string
+		certPEM, keyPEM []byte
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "dynamic-cert-test")
+		Expect(err).NotTo(HaveOccurred())
+		certPEM, keyPEM = selfSignedPair("initial")
+		Expect(os.WriteFile(filepath.Join(dir, "tls.crt"), certPEM, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "tls.key"), keyPEM, 0600)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("loads the initial cert/key pair on construction", func() {
+		p, err := tls.NewDynamicFileCertKeyContentProvider("test", dir, "tls.crt", "tls.key")
+		Expect(err).NotTo(HaveOccurred())
+		cert, key := p.CurrentCertKeyContent()
+		Expect(cert).To(Equal(certPEM))
+		Expect(key).To(Equal(keyPEM))
+	})
+
+	It("picks up an atomic file replacement and notifies listeners via Start", func() {
+		p, err := tls.NewDynamicFileCertKeyContentProvider("test", dir, "tls.crt", "tls.key")
+		Expect(err).NotTo(HaveOccurred())
+
+		listener := &countingListener{}
+		p.AddListener(listener)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go p.Start(ctx, 10*time.Millisecond)
+
+		newCertPEM, newKeyPEM := selfSignedPair("rotated")
+		// Write to temp files and rename into place, the same atomic-replacement pattern
+		// WriteCertificateToFile uses.
+		Expect(os.WriteFile(filepath.Join(dir, "tls.crt.tmp"), newCertPEM, 0644)).To(Succeed())
+		Expect(os.Rename(filepath.Join(dir, "tls.crt.tmp"), filepath.Join(dir, "tls.crt"))).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "tls.key.tmp"), newKeyPEM, 0600)).To(Succeed())
+		Expect(os.Rename(filepath.Join(dir, "tls.key.tmp"), filepath.Join(dir, "tls.key"))).To(Succeed())
+
+		Eventually(func() []byte {
+			cert, _ := p.CurrentCertKeyContent()
+			return cert
+		}, time.Second, 10*time.Millisecond).Should(Equal(newCertPEM))
+
+		cert, key := p.CurrentCertKeyContent()
+		Expect(cert).To(Equal(newCertPEM))
+		Expect(key).To(Equal(newKeyPEM))
+		Expect(atomic.LoadInt32(&listener.count)).To(BeNumerically(">=", 1))
+	})
+})
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+)
+
+// pqPublicKeyExtensionOID carries the ML-DSA-65 public key of a hybrid key pair as a non-critical
+// extension on the CSR, so a CA that understands hybrid mode can bind the PQ key to the issued
+// certificate while a CA that doesn't can safely ignore (or strip) the extension.
+var pqPublicKeyExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 0, 44363, 1}
+
+// HybridKeyPair pairs a classical private key with an ML-DSA-65 (post-quantum) private key.
+// It implements crypto.Signer over the classical key so it can be handed to
+// x509.CreateCertificateRequest unchanged; the PQ signature is computed separately by
+// signHybridCSR and travels alongside the CSR rather than inside its classical signature.
+type HybridKeyPair struct {
+	Classical crypto.Signer
+
+	PQPublicKey  *mldsa65.PublicKey
+	PQPrivateKey *mldsa65.PrivateKey
+}
+
+func (h *HybridKeyPair) Public() crypto.PublicKey {
+	return h.Classical.Public()
+}
+
+func (h *HybridKeyPair) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return h.Classical.Sign(rand, digest, opts)
+}
+
+// genEd25519 generates an Ed25519 private key, PKCS#8-encoded as that's the only encoding
+// crypto/x509 supports for Ed25519 keys.
+func genEd25519() (ed25519.PrivateKey, []byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	buf := bytes.NewBuffer([]byte{})
+	err = pem.Encode(buf, &pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return key, buf.Bytes(), err
+}
+
+// genHybrid generates a classical key (selected by classicalAlgorithm, one of the algorithms
+// GeneratePrivateKey already supports) alongside an ML-DSA-65 key, and PEM-encodes both private
+// keys as two concatenated blocks so a downstream consumer that only understands the classical
+// key can parse the first block and ignore the rest.
+func genHybrid(classicalAlgorithm string) (*HybridKeyPair, []byte, error) {
+	classicalKey, classicalPEM, err := GeneratePrivateKey(classicalAlgorithm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate classical half of hybrid key: %w", err)
+	}
+	signer, ok := classicalKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("classical algorithm %q does not produce a crypto.Signer", classicalAlgorithm)
+	}
+
+	pqPub, pqPriv, err := mldsa65.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate ML-DSA-65 half of hybrid key: %w", err)
+	}
+
+	pqDER, err := pqPriv.MarshalBinary()
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to marshal ML-DSA-65 private key: %w", err)
+	}
+
+	buf := bytes.NewBuffer(classicalPEM)
+	if err := pem.Encode(buf, &pem.Block{Type: "ML-DSA-65 PRIVATE KEY", Bytes: pqDER}); err != nil {
+		return nil, nil, err
+	}
+
+	return &HybridKeyPair{Classical: signer, PQPublicKey: pqPub, PQPrivateKey: pqPriv}, buf.Bytes(), nil
+}
+
+// signHybridCSR computes the ML-DSA-65 companion signature over the classically-signed CSR's DER
+// bytes. Verifiers that support hybrid mode check both signatures; verifiers that don't can strip
+// the companion signature and rely on the classical signature alone.
+func signHybridCSR(hybrid *HybridKeyPair, csrDER []byte) ([]byte, error) {
+	return mldsa65.SignDeterministic(hybrid.PQPrivateKey, csrDER)
+}
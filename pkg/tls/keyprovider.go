@@ -0,0 +1,62 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"crypto"
+	"fmt"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+// KeyProvider abstracts where a certificate's private key lives and who signs with it, so
+// CreateX509CSR doesn't need to know whether the key was generated in-process or lives in an
+// HSM or cloud KMS.
+type KeyProvider interface {
+	// NewKey returns a crypto.Signer to sign the CSR (and any certs its key is later used with),
+	// and the PEM encoding of its private key to write to Config.EmptyDirLocation. privateKeyPEM
+	// is nil for providers (pkcs11, kms) whose key material never leaves the provider; callers
+	// must then configure the pod's consumer to reach the key through that provider directly.
+	NewKey(config *cfg.Config) (signer crypto.Signer, privateKeyPEM []byte, err error)
+}
+
+// NewKeyProvider returns the KeyProvider selected by config.KeyProvider.
+func NewKeyProvider(config *cfg.Config) (KeyProvider, error) {
+	switch config.KeyProvider {
+	case cfg.KeyProviderSoftware, "":
+		return softwareKeyProvider{}, nil
+	case cfg.KeyProviderPKCS11:
+		return newPKCS11KeyProvider(config)
+	case cfg.KeyProviderKMS:
+		return newKMSKeyProvider(config)
+	default:
+		return nil, fmt.Errorf("unknown key provider: %q", config.KeyProvider)
+	}
+}
+
+// softwareKeyProvider generates the private key in-process, the historical behavior.
+type softwareKeyProvider struct{}
+
+func (softwareKeyProvider) NewKey(config *cfg.Config) (crypto.Signer, []byte, error) {
+	key, privateKeyPEM, err := GeneratePrivateKey(config.PrivateKeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("generated private key of type %T does not implement crypto.Signer", key)
+	}
+	return signer, privateKeyPEM, nil
+}
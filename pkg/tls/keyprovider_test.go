@@ -0,0 +1,56 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+// These dispatch checks live in an internal (package tls) test file, rather than alongside the
+// other tls_test specs, because softwareKeyProvider/kmsKeyProvider/pkcs11KeyProvider are
+// unexported, matching the pattern pkg/k8s/issuer_test.go uses for NewIssuer.
+var _ = Describe("Test NewKeyProvider", func() {
+	It("defaults to the software key provider when KeyProvider is unset", func() {
+		provider, err := NewKeyProvider(&cfg.Config{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider).To(BeAssignableToTypeOf(softwareKeyProvider{}))
+	})
+
+	It("selects the software key provider for KeyProviderSoftware", func() {
+		provider, err := NewKeyProvider(&cfg.Config{KeyProvider: cfg.KeyProviderSoftware})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(provider).To(BeAssignableToTypeOf(softwareKeyProvider{}))
+	})
+
+	It("dispatches to the PKCS#11 key provider for KeyProviderPKCS11", func() {
+		// There's no real token in this test environment, so this can't succeed, but it proves
+		// NewKeyProvider routed to the PKCS#11 path rather than silently falling through.
+		_, err := NewKeyProvider(&cfg.Config{KeyProvider: cfg.KeyProviderPKCS11})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("dispatches to the KMS key provider for KeyProviderKMS", func() {
+		_, err := NewKeyProvider(&cfg.Config{KeyProvider: cfg.KeyProviderKMS, KMSProvider: "bogus"})
+		Expect(err).To(MatchError(ContainSubstring("unknown KMS provider")))
+	})
+
+	It("errors for an unknown key provider", func() {
+		_, err := NewKeyProvider(&cfg.Config{KeyProvider: "bogus"})
+		Expect(err).To(HaveOccurred())
+	})
+})
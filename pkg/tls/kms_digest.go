@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+
+	awskmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+func parsePEMPublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("not a PEM-encoded public key")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func parseDERPublicKey(der []byte) (crypto.PublicKey, error) {
+	return x509.ParsePKIXPublicKey(der)
+}
+
+// digestAlgorithm returns hash unchanged if it is one KMS signing in this package supports
+// (SHA-256, SHA-384 or SHA-512), and an error otherwise; GCP KMS identifies the digest by which
+// field of kmspb.Digest is set rather than by name, so this exists only to produce a clear error
+// for an unsupported hash up front.
+func digestAlgorithm(hash crypto.Hash) (crypto.Hash, error) {
+	switch hash {
+	case crypto.SHA256, crypto.SHA384, crypto.SHA512:
+		return hash, nil
+	default:
+		return 0, fmt.Errorf("unsupported digest algorithm for KMS signing: %v", hash)
+	}
+}
+
+// digestProto builds the oneof Digest message GCP KMS's AsymmetricSign expects.
+func digestProto(hash crypto.Hash, digest []byte) *kmspb.Digest {
+	switch hash {
+	case crypto.SHA384:
+		return &kmspb.Digest{Digest: &kmspb.Digest_Sha384{Sha384: digest}}
+	case crypto.SHA512:
+		return &kmspb.Digest{Digest: &kmspb.Digest_Sha512{Sha512: digest}}
+	default:
+		return &kmspb.Digest{Digest: &kmspb.Digest_Sha256{Sha256: digest}}
+	}
+}
+
+// awsSigningAlgorithm picks the AWS KMS SigningAlgorithmSpec matching publicKey's type and the
+// requested hash.
+func awsSigningAlgorithm(publicKey crypto.PublicKey, hash crypto.Hash) (awskmstypes.SigningAlgorithmSpec, error) {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			return awskmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha256, nil
+		case crypto.SHA384:
+			return awskmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha384, nil
+		case crypto.SHA512:
+			return awskmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha512, nil
+		}
+	case *ecdsa.PublicKey:
+		switch hash {
+		case crypto.SHA256:
+			return awskmstypes.SigningAlgorithmSpecEcdsaSha256, nil
+		case crypto.SHA384:
+			return awskmstypes.SigningAlgorithmSpecEcdsaSha384, nil
+		case crypto.SHA512:
+			return awskmstypes.SigningAlgorithmSpecEcdsaSha512, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported AWS KMS key/digest combination: %T/%v", publicKey, hash)
+}
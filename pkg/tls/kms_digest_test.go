@@ -0,0 +1,118 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+
+	awskmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+var _ = Describe("Test digestAlgorithm", func() {
+	It("accepts SHA256, SHA384 and SHA512", func() {
+		for _, hash := range []crypto.Hash{crypto.SHA256, crypto.SHA384, crypto.SHA512} {
+			got, err := digestAlgorithm(hash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(hash))
+		}
+	})
+
+	It("errors for an unsupported hash", func() {
+		_, err := digestAlgorithm(crypto.MD5)
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Test digestProto", func() {
+	digest := []byte("<digest>")
+
+	It("sets the Sha256 field by default", func() {
+		d := digestProto(crypto.SHA256, digest)
+		Expect(d.GetSha256()).To(Equal(digest))
+	})
+
+	It("sets the Sha384 field for SHA384", func() {
+		d := digestProto(crypto.SHA384, digest)
+		Expect(d.GetSha384()).To(Equal(digest))
+	})
+
+	It("sets the Sha512 field for SHA512", func() {
+		d := digestProto(crypto.SHA512, digest)
+		Expect(d.GetSha512()).To(Equal(digest))
+	})
+
+	It("falls back to the Sha256 field for an unrecognized hash", func() {
+		d := digestProto(crypto.MD5, digest)
+		Expect(d.GetSha256()).To(Equal(digest))
+	})
+})
+
+// Generated once at package load rather than inside a BeforeEach, since DescribeTable's Entry
+// values (below) are evaluated when the spec tree is built, before any BeforeEach runs.
+var (
+	rsaKey   = mustGenerateRSAKey()
+	ecdsaKey = mustGenerateECDSAKey()
+)
+
+func mustGenerateRSAKey() *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+func mustGenerateECDSAKey() *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+var _ = Describe("Test awsSigningAlgorithm", func() {
+	DescribeTable("picks the SigningAlgorithmSpec matching the key type and hash",
+		func(publicKey crypto.PublicKey, hash crypto.Hash, expected awskmstypes.SigningAlgorithmSpec) {
+			got, err := awsSigningAlgorithm(publicKey, hash)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(got).To(Equal(expected))
+		},
+		Entry("RSA/SHA256", &rsaKey.PublicKey, crypto.SHA256, awskmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha256),
+		Entry("RSA/SHA384", &rsaKey.PublicKey, crypto.SHA384, awskmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha384),
+		Entry("RSA/SHA512", &rsaKey.PublicKey, crypto.SHA512, awskmstypes.SigningAlgorithmSpecRsassaPkcs1V15Sha512),
+		Entry("ECDSA/SHA256", &ecdsaKey.PublicKey, crypto.SHA256, awskmstypes.SigningAlgorithmSpecEcdsaSha256),
+		Entry("ECDSA/SHA384", &ecdsaKey.PublicKey, crypto.SHA384, awskmstypes.SigningAlgorithmSpecEcdsaSha384),
+		Entry("ECDSA/SHA512", &ecdsaKey.PublicKey, crypto.SHA512, awskmstypes.SigningAlgorithmSpecEcdsaSha512),
+	)
+
+	It("errors for an unsupported key/digest combination", func() {
+		_, err := awsSigningAlgorithm(&rsaKey.PublicKey, crypto.MD5)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors for an unsupported key type", func() {
+		_, err := awsSigningAlgorithm("not a key", crypto.SHA256)
+		Expect(err).To(HaveOccurred())
+	})
+})
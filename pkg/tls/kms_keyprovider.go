@@ -0,0 +1,157 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+
+	kms "cloud.google.com/go/kms/apiv1"
+	kmspb "google.golang.org/genproto/googleapis/cloud/kms/v1"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	awskms "github.com/aws/aws-sdk-go-v2/service/kms"
+	awskmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// kmsKeyProvider signs with an asymmetric key held in a cloud KMS, selected by config.KMSProvider.
+// The key is never generated or stored by this provider; config.KMSKeyID must already refer to an
+// existing asymmetric signing key.
+type kmsKeyProvider struct {
+	signer crypto.Signer
+}
+
+func newKMSKeyProvider(config *cfg.Config) (KeyProvider, error) {
+	switch config.KMSProvider {
+	case "gcp":
+		signer, err := newGCPKMSSigner(config)
+		if err != nil {
+			return nil, err
+		}
+		return &kmsKeyProvider{signer: signer}, nil
+	case "aws":
+		signer, err := newAWSKMSSigner(config)
+		if err != nil {
+			return nil, err
+		}
+		return &kmsKeyProvider{signer: signer}, nil
+	default:
+		return nil, fmt.Errorf("unknown KMS provider: %q (expected \"gcp\" or \"aws\")", config.KMSProvider)
+	}
+}
+
+func (p *kmsKeyProvider) NewKey(config *cfg.Config) (crypto.Signer, []byte, error) {
+	return p.signer, nil, nil
+}
+
+// gcpKMSSigner signs with a GCP Cloud KMS asymmetric CryptoKeyVersion.
+type gcpKMSSigner struct {
+	client    *kms.KeyManagementClient
+	keyName   string
+	publicKey crypto.PublicKey
+}
+
+func newGCPKMSSigner(config *cfg.Config) (*gcpKMSSigner, error) {
+	ctx := context.Background()
+	client, err := kms.NewKeyManagementClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create GCP KMS client: %w", err)
+	}
+
+	pub, err := client.GetPublicKey(ctx, &kmspb.GetPublicKeyRequest{Name: config.KMSKeyID})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch GCP KMS public key %q: %w", config.KMSKeyID, err)
+	}
+	publicKey, err := parsePEMPublicKey([]byte(pub.Pem))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse GCP KMS public key %q: %w", config.KMSKeyID, err)
+	}
+
+	return &gcpKMSSigner{client: client, keyName: config.KMSKeyID, publicKey: publicKey}, nil
+}
+
+func (s *gcpKMSSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *gcpKMSSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	algo, err := digestAlgorithm(opts.HashFunc())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.AsymmetricSign(context.Background(), &kmspb.AsymmetricSignRequest{
+		Name:   s.keyName,
+		Digest: digestProto(algo, digest),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GCP KMS sign request failed: %w", err)
+	}
+	return resp.Signature, nil
+}
+
+// awsKMSSigner signs with an AWS KMS asymmetric signing key.
+type awsKMSSigner struct {
+	client    *awskms.Client
+	keyID     string
+	publicKey crypto.PublicKey
+}
+
+func newAWSKMSSigner(config *cfg.Config) (*awsKMSSigner, error) {
+	ctx := context.Background()
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(config.KMSRegion))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	client := awskms.NewFromConfig(awsCfg)
+
+	pub, err := client.GetPublicKey(ctx, &awskms.GetPublicKeyInput{KeyId: aws.String(config.KMSKeyID)})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch AWS KMS public key %q: %w", config.KMSKeyID, err)
+	}
+	publicKey, err := parseDERPublicKey(pub.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse AWS KMS public key %q: %w", config.KMSKeyID, err)
+	}
+
+	return &awsKMSSigner{client: client, keyID: config.KMSKeyID, publicKey: publicKey}, nil
+}
+
+func (s *awsKMSSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s *awsKMSSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	signingAlgorithm, err := awsSigningAlgorithm(s.publicKey, opts.HashFunc())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Sign(context.Background(), &awskms.SignInput{
+		KeyId:            aws.String(s.keyID),
+		Message:          digest,
+		MessageType:      awskmstypes.MessageTypeDigest,
+		SigningAlgorithm: signingAlgorithm,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("AWS KMS sign request failed: %w", err)
+	}
+	return resp.Signature, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Tigera, Inc. All rights reserved.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tls
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"fmt"
+
+	"github.com/ThalesIgnite/crypto11"
+	"github.com/tigera/key-cert-provisioner/pkg/cfg"
+)
+
+// pkcs11KeyProvider signs with a key held in a PKCS#11 token (an HSM, or a software token such as
+// SoftHSM), identified by PKCS11Label. The key is generated in the token on first use and reused
+// on every later call with the same label, so renewals keep signing with the same key.
+type pkcs11KeyProvider struct {
+	ctx *crypto11.Context
+}
+
+func newPKCS11KeyProvider(config *cfg.Config) (KeyProvider, error) {
+	ctx, err := crypto11.Configure(&crypto11.Config{
+		Path:       config.PKCS11Module,
+		SlotNumber: intPtr(int(config.PKCS11Slot)),
+		Pin:        config.PKCS11Pin,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open PKCS#11 token: %w", err)
+	}
+	return &pkcs11KeyProvider{ctx: ctx}, nil
+}
+
+func (p *pkcs11KeyProvider) NewKey(config *cfg.Config) (crypto.Signer, []byte, error) {
+	label := []byte(config.PKCS11Label)
+
+	signer, err := p.ctx.FindKeyPair(nil, label)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to look up PKCS#11 key %q: %w", config.PKCS11Label, err)
+	}
+	if signer != nil {
+		return signer, nil, nil
+	}
+
+	signer, err = p.ctx.GenerateECDSAKeyPairWithLabel(label, label, elliptic.P256())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate PKCS#11 key %q: %w", config.PKCS11Label, err)
+	}
+	return signer, nil, nil
+}
+
+// intPtr is a small helper since crypto11.Config wants a *int for an optional slot number.
+func intPtr(i int) *int {
+	return &i
+}
@@ -34,6 +34,12 @@ type X509CSR struct {
 	PrivateKey    interface{}
 	PrivateKeyPEM []byte
 	CSR           []byte
+
+	// PQSignature is the ML-DSA-65 companion signature over the DER bytes of CSR (not its PEM
+	// encoding), present only when the
+	// configured private key algorithm selects a hybrid PQ profile. A downstream signer that
+	// doesn't support hybrid mode can safely ignore it and verify CSR classically.
+	PQSignature []byte
 }
 
 // CreateX509CSR creates a certificate signing request based on a configuration.
@@ -79,22 +85,50 @@ func CreateX509CSR(config *cfg.Config) (*X509CSR, error) {
 			},
 		},
 	}
-	privateKey, privateKeyPem, err := GeneratePrivateKey(config.NewPrivateKey)
+	keyProvider, err := NewKeyProvider(config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize key provider: %w", err)
+	}
+	privateKey, privateKeyPem, err := keyProvider.NewKey(config)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create private key: %w", err)
 	}
+
+	var hybrid *HybridKeyPair
+	if h, ok := privateKey.(*HybridKeyPair); ok {
+		hybrid = h
+		pqPub, err := hybrid.PQPublicKey.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal ML-DSA-65 public key: %w", err)
+		}
+		csrTemplate.ExtraExtensions = append(csrTemplate.ExtraExtensions, pkix.Extension{
+			Id:       pqPublicKeyExtensionOID,
+			Value:    pqPub,
+			Critical: false,
+		})
+	}
+
 	// step: generate the csr request
 	csrCertificate, err := x509.CreateCertificateRequest(rand.Reader, &csrTemplate, privateKey)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create an x509 csr: %w", err)
 	}
-	return &X509CSR{
+
+	x509CSR := &X509CSR{
 		PrivateKey:    privateKey,
 		PrivateKeyPEM: privateKeyPem,
 		CSR: pem.EncodeToMemory(&pem.Block{
 			Type: "CERTIFICATE REQUEST", Bytes: csrCertificate,
 		}),
-	}, nil
+	}
+
+	if hybrid != nil {
+		x509CSR.PQSignature, err = signHybridCSR(hybrid, csrCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create ML-DSA-65 companion signature: %w", err)
+		}
+	}
+	return x509CSR, nil
 }
 
 // basicConstraints is a struct needed for creating a template.
@@ -125,6 +159,15 @@ func GeneratePrivateKey(algorithm string) (interface{}, []byte, error) {
 	case "ECDSAWithCurve521":
 		return genECDSA(elliptic.P521())
 
+	case "Ed25519":
+		return genEd25519()
+
+	case "Ed25519+ML-DSA-65":
+		return genHybrid("Ed25519")
+
+	case "ECDSAWithCurve256+ML-DSA-65":
+		return genHybrid("ECDSAWithCurve256")
+
 	default:
 		return genRSA(2048)
 	}
@@ -181,6 +224,9 @@ func SignatureAlgorithm(algorithm string) x509.SignatureAlgorithm {
 	case "ECDSAWithSHA512":
 		return x509.ECDSAWithSHA512
 
+	case "PureEd25519":
+		return x509.PureEd25519
+
 	default:
 		return x509.SHA256WithRSA
 	}